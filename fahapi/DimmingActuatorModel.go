@@ -1,10 +1,10 @@
 package fahapi
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
-	"strconv"
 )
 
 type DimmingActuatorUnit struct {
@@ -15,6 +15,8 @@ type DimmingActuatorUnit struct {
 	DimmingValueSet bool
 	Force           bool
 	ForceSet        bool
+	ErrorCode       int // AL_INFO_ERROR, 0 = no fault
+	ErrorCodeSet    bool
 }
 
 const UntTypeDimmingActuator UnitTypeConst = "AcDimmin"
@@ -31,28 +33,50 @@ func (dau *DimmingActuatorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) boo
 	changed := false
 
 	switch *outPut.PairingID {
-	case 0x0100: // AL_INFO_ON_OFF (Reflects the binary state of the actuator)
-		on := *outPut.Value != "0"
+	case AL_INFO_ON_OFF: // Reflects the binary state of the actuator
+		value, ok := dau.decode(outPut)
+		if !ok {
+			break
+		}
+		on := value.Bool
 		if on != dau.On {
 			dau.On = on
 			dau.OnSet = true
 			changed = true
 		}
-	case 0x0101: // AL_INFO_FORCE (Indicates the cause of forced operation (0 = not forced))
-		force := *outPut.Value != "0"
+	case AL_INFO_FORCE: // Indicates the cause of forced operation (0 = not forced)
+		value, ok := dau.decode(outPut)
+		if !ok {
+			break
+		}
+		force := value.Bool
 		if force != dau.Force {
 			dau.Force = force
 			dau.ForceSet = true
 			changed = true
 		}
-	case 0x0110: // AL_INFO_ACTUAL_DIMMING_VALUE
-		dimmingValue, _ := strconv.Atoi(*outPut.Value)
+	case AL_INFO_ACTUAL_DIMMING_VALUE:
+		value, ok := dau.decode(outPut)
+		if !ok {
+			break
+		}
+		dimmingValue := value.Int
 		if math.Abs(float64(dau.DimmingValue-dimmingValue)) >= 1 {
 			dau.DimmingValue = dimmingValue
 			dau.DimmingValueSet = true
 			changed = true
 		}
-	case 0x0111: // AL_INFO_ERROR (Indicates load failures / short circuits / etc)
+	case AL_INFO_ERROR: // Indicates load failures / short circuits / etc
+		value, ok := dau.decode(outPut)
+		if !ok {
+			break
+		}
+		errorCode := value.Int
+		if errorCode != dau.ErrorCode {
+			dau.ErrorCode = errorCode
+			dau.ErrorCodeSet = true
+			changed = true
+		}
 	}
 
 	return changed
@@ -62,6 +86,31 @@ func (dau *DimmingActuatorUnit) resetChanged() {
 	dau.OnSet = false
 	dau.DimmingValueSet = false
 	dau.ForceSet = false
+	dau.ErrorCodeSet = false
+}
+
+// HasFault reports whether the actuator signals a load failure, short circuit etc. (AL_INFO_ERROR).
+func (dau *DimmingActuatorUnit) HasFault() bool {
+	return dau.ErrorCode != 0
+}
+
+func (dau *DimmingActuatorUnit) snapshot() Unit {
+	snapshot := *dau
+	snapshot.UnitData = dau.snapshotData()
+	return &snapshot
+}
+
+// SetOn switches the light on with its last brightness or off (AL_SWITCH_ON_OFF).
+func (dau *DimmingActuatorUnit) SetOn(ctx context.Context, on bool) error {
+	return dau.putInput(ctx, AL_SWITCH_ON_OFF, on)
+}
+
+// SetBrightness dims the light to the given brightness in percent, 0 switches it off (AL_ABSOLUTE_SET_VALUE_CONTROL).
+func (dau *DimmingActuatorUnit) SetBrightness(ctx context.Context, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("illegal brightness %d, has to be 0..100: %w", percent, ErrInvalidValue)
+	}
+	return dau.putInput(ctx, AL_ABSOLUTE_SET_VALUE_CONTROL, percent)
 }
 
 func (dau *DimmingActuatorUnit) String() string {
@@ -73,12 +122,15 @@ func (dau *DimmingActuatorUnit) String() string {
 	if dau.Force {
 		force = " (forced)"
 	}
+	if dau.HasFault() {
+		force += fmt.Sprintf(" FAULT %d", dau.ErrorCode)
+	}
 	return fmt.Sprintf("%s %s: %s %2d%%%s", dau.prtUnitHead(), *dau.GetChannel().DisplayName, on, dau.DimmingValue, force)
 }
 
-func dimmingActuatorFactory(deviceId string, device *Device, channelId string) Unit {
+func dimmingActuatorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	dau := DimmingActuatorUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeDimmingActuator),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeDimmingActuator),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
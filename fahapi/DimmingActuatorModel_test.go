@@ -0,0 +1,48 @@
+package fahapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDimmingActuatorSetBrightness(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testDevice("Dimmer", FID_DIMMING_ACTUATOR,
+			map[string]*InOutPut{
+				"idp0000": datapoint(AL_SWITCH_ON_OFF, "0"),
+				"idp0002": datapoint(AL_ABSOLUTE_SET_VALUE_CONTROL, "0"),
+			},
+			map[string]*InOutPut{
+				"odp0000": datapoint(AL_INFO_ON_OFF, "0"),
+				"odp0001": datapoint(AL_INFO_ACTUAL_DIMMING_VALUE, "0"),
+			}),
+	})
+	c := startClient(t, f)
+	dau := CastDAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+
+	tests := []struct {
+		percent  int
+		wantBody string // "" if the value has to be rejected
+	}{
+		{0, "0"},
+		{55, "55"},
+		{100, "100"},
+		{101, ""},
+		{-1, ""},
+	}
+	wantPath := "/api/rest/datapoint/" + testSysAP + "/ABB700000001.ch0000.idp0002"
+	for _, test := range tests {
+		before := len(f.recordedPuts())
+		err := dau.SetBrightness(testContext(t), test.percent)
+		puts := f.recordedPuts()[before:]
+		if test.wantBody == "" {
+			if !errors.Is(err, ErrInvalidValue) || len(puts) > 0 {
+				t.Errorf("SetBrightness(%d): got error %v and PUTs %+v, want ErrInvalidValue", test.percent, err, puts)
+			}
+			continue
+		}
+		if err != nil || len(puts) != 1 || puts[0].Path != wantPath || puts[0].Body != test.wantBody {
+			t.Errorf("SetBrightness(%d): got error %v and PUTs %+v, want %s to %s", test.percent, err, puts, test.wantBody, wantPath)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package fahapi
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error kinds of failed REST calls. Use them with errors.Is:
+//
+//	if errors.Is(err, fahapi.ErrUnauthorized) { ... }
+var (
+	ErrUnauthorized = errors.New("unauthorized")    // wrong username or password (401, 403)
+	ErrNotFound     = errors.New("not found")       // unknown SysAP, device, channel or datapoint (404)
+	ErrBadDatapoint = errors.New("bad datapoint")   // malformed datapoint or value (400, 422)
+	ErrSysAPBusy    = errors.New("SysAP busy")      // SysAP temporarily not able to handle the request (429, 502, 503, 504)
+	ErrAPI          = errors.New("SysAP API error") // any other non-200 response
+)
+
+// ErrInvalidValue is the kind of all errors about datapoint values, which don't match their pairing id.
+var ErrInvalidValue = errors.New("invalid datapoint value")
+
+// Reasons why a tracked write (see UnitData.Write) was not confirmed.
+var (
+	ErrWriteTimeout      = errors.New("write not confirmed in time")
+	ErrWriteContradicted = errors.New("write contradicted by device")
+)
+
+// APIError is returned by all REST calls, if the SysAP answers with a status other than 200.
+// Get it with errors.As to access the HTTP status and the Error payload sent by the SysAP.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Payload    *Error // nil, if the body didn't contain an Error model
+	Body       []byte
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s url %s returned code %d (%s)", e.Method, e.URL, e.StatusCode, e.Status)
+	if e.Payload != nil {
+		if e.Payload.Title != nil {
+			msg += ": " + *e.Payload.Title
+		}
+		if e.Payload.Detail != nil {
+			msg += " - " + *e.Payload.Detail
+		}
+		if e.Payload.Code != nil {
+			msg += " [" + *e.Payload.Code + "]"
+		}
+	}
+	return msg
+}
+
+// Unwrap returns the error kind (ErrUnauthorized, ErrNotFound, ...).
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func newAPIError(method string, url string, response *http.Response, body []byte) *APIError {
+	return &APIError{
+		Method:     method,
+		URL:        url,
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Payload:    parseErrorPayload(body),
+		Body:       body,
+		kind:       errorKindForStatus(response.StatusCode),
+	}
+}
+
+func errorKindForStatus(statusCode int) error {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrBadDatapoint
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrSysAPBusy
+	}
+	return ErrAPI
+}
+
+// parseErrorPayload decodes the Error model, either sent directly or keyed by the SysAP UUID.
+func parseErrorPayload(body []byte) *Error {
+	var direct Error
+	if err := json2.Unmarshal(body, &direct); err == nil && (direct.Code != nil || direct.Title != nil || direct.Detail != nil) {
+		return &direct
+	}
+
+	var perSysAP map[string]struct {
+		Error *Error `json:"error"`
+	}
+	if err := json2.Unmarshal(body, &perSysAP); err == nil {
+		for _, sysap := range perSysAP {
+			if sysap.Error != nil {
+				return sysap.Error
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValueError reports a datapoint value which doesn't match the value type of its pairing id.
+type ValueError struct {
+	PairingId int
+	Value     string
+	Reason    string
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %s", e.Value, PairingIdName(e.PairingId), e.Reason)
+}
+
+// Unwrap returns ErrInvalidValue.
+func (e *ValueError) Unwrap() error {
+	return ErrInvalidValue
+}
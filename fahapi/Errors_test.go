@@ -0,0 +1,121 @@
+package fahapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewAPIErrorKind(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadDatapoint},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnprocessableEntity, ErrBadDatapoint},
+		{http.StatusTooManyRequests, ErrSysAPBusy},
+		{http.StatusInternalServerError, ErrAPI},
+		{http.StatusBadGateway, ErrSysAPBusy},
+		{http.StatusServiceUnavailable, ErrSysAPBusy},
+		{http.StatusGatewayTimeout, ErrSysAPBusy},
+		{http.StatusTeapot, ErrAPI},
+	}
+
+	kinds := []error{ErrUnauthorized, ErrNotFound, ErrBadDatapoint, ErrSysAPBusy, ErrAPI}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			response := &http.Response{StatusCode: test.status, Status: fmt.Sprintf("%d %s", test.status, http.StatusText(test.status))}
+			err := fmt.Errorf("wrapped: %w", newAPIError(http.MethodGet, "http://sysap/api", response, nil))
+
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == test.want) {
+					t.Errorf("errors.Is(%v) = %v, want kind %v", kind, errors.Is(err, kind), test.want)
+				}
+			}
+			var apiError *APIError
+			if !errors.As(err, &apiError) {
+				t.Fatalf("errors.As didn't find the APIError in %v", err)
+			}
+			if apiError.StatusCode != test.status || apiError.Method != http.MethodGet || apiError.URL != "http://sysap/api" {
+				t.Errorf("got %+v", apiError)
+			}
+		})
+	}
+}
+
+func TestNewAPIErrorPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		want      *Error // nil: no payload
+		wantError string
+	}{
+		{
+			name:      "direct",
+			body:      `{"code":"E1","title":"Bad value","detail":"out of range"}`,
+			want:      &Error{Code: strPtr("E1"), Title: strPtr("Bad value"), Detail: strPtr("out of range")},
+			wantError: "PUT url http://sysap/api returned code 400 (400 Bad Request): Bad value - out of range [E1]",
+		},
+		{
+			name:      "keyed by SysAP",
+			body:      `{"` + testSysAP + `":{"error":{"title":"Unknown datapoint"}}}`,
+			want:      &Error{Title: strPtr("Unknown datapoint")},
+			wantError: "PUT url http://sysap/api returned code 400 (400 Bad Request): Unknown datapoint",
+		},
+		{
+			name:      "JSON without error",
+			body:      `{"` + testSysAP + `":{"values":["1"]}}`,
+			wantError: "PUT url http://sysap/api returned code 400 (400 Bad Request)",
+		},
+		{
+			name:      "not JSON",
+			body:      "<html>Bad Request</html>",
+			wantError: "PUT url http://sysap/api returned code 400 (400 Bad Request)",
+		},
+		{
+			name:      "empty",
+			wantError: "PUT url http://sysap/api returned code 400 (400 Bad Request)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := &http.Response{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}
+			var err error = newAPIError(http.MethodPut, "http://sysap/api", response, []byte(test.body))
+
+			var apiError *APIError
+			if !errors.As(err, &apiError) {
+				t.Fatalf("errors.As didn't find the APIError in %v", err)
+			}
+			if string(apiError.Body) != test.body {
+				t.Errorf("body %q, want %q", apiError.Body, test.body)
+			}
+			if got, want := errorPayloadString(apiError.Payload), errorPayloadString(test.want); got != want {
+				t.Errorf("payload %s, want %s", got, want)
+			}
+			if err.Error() != test.wantError {
+				t.Errorf("error %q, want %q", err.Error(), test.wantError)
+			}
+			if !errors.Is(err, ErrBadDatapoint) {
+				t.Errorf("error isn't ErrBadDatapoint")
+			}
+		})
+	}
+}
+
+func errorPayloadString(e *Error) string {
+	if e == nil {
+		return "<nil>"
+	}
+	field := func(s *string) string {
+		if s == nil {
+			return "<nil>"
+		}
+		return *s
+	}
+	return fmt.Sprintf("{code %s title %s detail %s}", field(e.Code), field(e.Title), field(e.Detail))
+}
@@ -0,0 +1,250 @@
+package fahapi
+
+import (
+	json2 "encoding/json"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	EventUnitChanged            EventType = "UnitChanged"
+	EventDeviceAdded            EventType = "DeviceAdded"
+	EventDeviceRemoved          EventType = "DeviceRemoved"
+	EventDeviceMetadataChanged  EventType = "DeviceMetadataChanged"
+	EventSceneTriggered         EventType = "SceneTriggered"
+	EventConnectionStateChanged EventType = "ConnectionStateChanged"
+	EventError                  EventType = "Error"
+)
+
+// Event is delivered to the subscribers of a client (see Subscribe).
+type Event interface {
+	Type() EventType
+	Time() time.Time
+}
+
+type eventHead struct {
+	At time.Time
+}
+
+func (e eventHead) Time() time.Time { return e.At }
+
+// UnitChangedEvent carries snapshots of a unit before and after the change.
+// Old is nil for a unit which didn't exist before.
+type UnitChangedEvent struct {
+	eventHead
+	Key string
+	Old Unit
+	New Unit
+}
+
+func (e *UnitChangedEvent) Type() EventType { return EventUnitChanged }
+
+// DeviceAddedEvent carries a copy of a device which is new on the SysAP.
+type DeviceAddedEvent struct {
+	eventHead
+	SysApId  string
+	DeviceId string
+	Device   *Device
+}
+
+func (e *DeviceAddedEvent) Type() EventType { return EventDeviceAdded }
+
+// DeviceRemovedEvent reports a device which was removed from the SysAP, together with the keys of its units.
+type DeviceRemovedEvent struct {
+	eventHead
+	SysApId  string
+	DeviceId string
+	UnitKeys []string
+}
+
+func (e *DeviceRemovedEvent) Type() EventType { return EventDeviceRemoved }
+
+// DeviceMetadataChangedEvent reports a device which was renamed, moved to another room, got new channels etc.
+// Old and New are copies of the device before and after the change. The units of the device were hydrated
+// again and are reported as UnitChangedEvent too.
+type DeviceMetadataChangedEvent struct {
+	eventHead
+	SysApId  string
+	DeviceId string
+	Old      *Device
+	New      *Device
+}
+
+func (e *DeviceMetadataChangedEvent) Type() EventType { return EventDeviceMetadataChanged }
+
+// SceneTriggeredEvent reports a scene triggered on the SysAP (e.g. via a wall panel).
+type SceneTriggeredEvent struct {
+	eventHead
+	SysApId  string
+	SceneId  string              // device serial of the scene
+	Scene    *Scene              // nil, if the scene is unknown to us
+	Channels map[string]*Channel // channels sent with the trigger, if the payload contained any
+	Raw      json2.RawMessage    // payload as sent by the SysAP
+}
+
+func (e *SceneTriggeredEvent) Type() EventType { return EventSceneTriggered }
+
+type ConnectionState string
+
+const (
+	ConnectionConnected    ConnectionState = "connected"
+	ConnectionDisconnected ConnectionState = "disconnected"
+	ConnectionStopped      ConnectionState = "stopped"
+)
+
+// ConnectionStateChangedEvent reports the state of the websocket connection. Err is set, if the connection was lost.
+type ConnectionStateChangedEvent struct {
+	eventHead
+	State ConnectionState
+	Err   error
+}
+
+func (e *ConnectionStateChangedEvent) Type() EventType { return EventConnectionStateChanged }
+
+// ErrorEvent carries an error which occurred while processing websocket messages.
+type ErrorEvent struct {
+	eventHead
+	Err error
+}
+
+func (e *ErrorEvent) Type() EventType { return EventError }
+
+// EventFilter decides whether an event is delivered to a subscriber. It runs on the websocket reader
+// goroutine, so it has to be fast and must not call methods of the client.
+type EventFilter func(event Event) bool
+
+// EventTypeFilter returns a filter which lets only the given event types pass.
+func EventTypeFilter(types ...EventType) EventFilter {
+	return func(event Event) bool {
+		for _, t := range types {
+			if event.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription delivers events on the channel C until it is closed.
+type Subscription struct {
+	C <-chan Event
+
+	client  *Client
+	events  chan Event
+	filter  EventFilter
+	dropped uint64 // guarded by client.subscriberMutex
+}
+
+// Subscribe returns a new subscription with a channel buffer of bufferSize events. A nil filter lets all
+// events pass. If the buffer of a subscriber is full, new events for this subscriber are dropped
+// (see Dropped), so a slow consumer never stalls the websocket reader.
+func (c *Client) Subscribe(bufferSize int, filter EventFilter) *Subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	events := make(chan Event, bufferSize)
+	subscription := &Subscription{
+		C:      events,
+		client: c,
+		events: events,
+		filter: filter,
+	}
+
+	c.subscriberMutex.Lock()
+	c.subscribers = append(c.subscribers, subscription)
+	c.subscriberMutex.Unlock()
+
+	return subscription
+}
+
+// Close ends the subscription and closes its channel.
+func (s *Subscription) Close() {
+	c := s.client
+	c.subscriberMutex.Lock()
+	defer c.subscriberMutex.Unlock()
+
+	for i, subscription := range c.subscribers {
+		if subscription == s {
+			c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+			close(s.events)
+			return
+		}
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer of the subscription was full.
+func (s *Subscription) Dropped() uint64 {
+	s.client.subscriberMutex.Lock()
+	defer s.client.subscriberMutex.Unlock()
+	return s.dropped
+}
+
+func (c *Client) hasSubscribers() bool {
+	c.subscriberMutex.Lock()
+	defer c.subscriberMutex.Unlock()
+	return len(c.subscribers) > 0
+}
+
+// publish delivers the events to all subscribers without blocking.
+func (c *Client) publish(events ...Event) {
+	c.subscriberMutex.Lock()
+	defer c.subscriberMutex.Unlock()
+
+	for _, event := range events {
+		for _, subscription := range c.subscribers {
+			if subscription.filter != nil && !subscription.filter(event) {
+				continue
+			}
+			select {
+			case subscription.events <- event:
+			default:
+				subscription.dropped++
+			}
+		}
+	}
+}
+
+// queueEvent remembers an event to be published by publishQueuedEvents.
+// It is used while holding stateMutex; the caller has to hold its write lock.
+func (c *Client) queueEvent(event Event) {
+	c.queuedEvents = append(c.queuedEvents, event)
+}
+
+// queueError remembers an error to be reported by publishQueuedEvents.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) queueError(err error) {
+	c.queuedErrors = append(c.queuedErrors, err)
+}
+
+// publishQueuedEvents publishes all queued events and reports the queued errors. The caller must not hold stateMutex.
+func (c *Client) publishQueuedEvents() {
+	c.stateMutex.Lock()
+	events := c.queuedEvents
+	c.queuedEvents = nil
+	errs := c.queuedErrors
+	c.queuedErrors = nil
+	c.stateMutex.Unlock()
+
+	if len(events) > 0 {
+		c.publish(events...)
+	}
+	for _, err := range errs {
+		c.reportError(err)
+	}
+}
+
+// rememberUnitBeforeChange stores the snapshot of a unit before its first change since the last
+// handleUpdatedUnits, to publish it as old value of the UnitChangedEvent. before is nil for new units.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) rememberUnitBeforeChange(key string, before Unit) {
+	if _, ok := c.unitsBeforeChange[key]; ok {
+		return
+	}
+	c.unitsBeforeChange[key] = before
+}
+
+func newEventHead() eventHead {
+	return eventHead{At: time.Now()}
+}
@@ -0,0 +1,45 @@
+package fahapi
+
+import "testing"
+
+func TestSubscriptions(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+	c := startClient(t, f)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	all := c.Subscribe(10, nil)
+	defer all.Close()
+	units := c.Subscribe(10, EventTypeFilter(EventUnitChanged))
+	defer units.Close()
+	slow := c.Subscribe(1, EventTypeFilter(EventUnitChanged))
+	defer slow.Close()
+	closed := c.Subscribe(10, nil)
+	closed.Close()
+	if _, ok := <-closed.C; ok {
+		t.Error("channel of a closed subscription is still open")
+	}
+
+	conn := startLoop(t, f, c)
+	if event := nextEvent(t, all).(*ConnectionStateChangedEvent); event.State != ConnectionConnected {
+		t.Errorf("got %s, want connected", event.State)
+	}
+
+	for _, value := range []string{"1", "0", "1"} {
+		sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": value})
+		event := nextEvent(t, units).(*UnitChangedEvent)
+		if event.Key != key || CastSAU(event.Old).On == CastSAU(event.New).On || CastSAU(event.New).On != (value == "1") {
+			t.Errorf("update %s: got %s from %v to %v", value, event.Key, event.Old, event.New)
+		}
+		if nextEvent(t, all).Type() != EventUnitChanged {
+			t.Errorf("unfiltered subscription missed the update %s", value)
+		}
+	}
+
+	// the slow subscriber only keeps the first event, the others are dropped without blocking the reader
+	if slow.Dropped() != 2 {
+		t.Errorf("dropped %d events, want 2", slow.Dropped())
+	}
+	if event := nextEvent(t, slow).(*UnitChangedEvent); !CastSAU(event.New).On {
+		t.Errorf("slow subscriber got %v, want the first update", event.New)
+	}
+}
@@ -0,0 +1,289 @@
+package fahapi
+
+import (
+	"context"
+	json2 "encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const testSysAP = "00000000-0000-0000-0000-000000000000"
+
+// fakeSysAP serves the parts of the local API used by the client: configuration, device, datapoint,
+// virtualdevice and the websocket. Websocket connections are handed to the test via conns.
+type fakeSysAP struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mutex            sync.Mutex
+	config           ApiRestConfigurationGet200ApplicationJsonResponse
+	puts             []fakePut
+	putResult        string            // result of datapoint PUTs, "OK" by default
+	putDelay         time.Duration     // delay of datapoint PUTs
+	virtualDeviceIds map[string]string // native serial -> device id assigned by the fake
+
+	conns chan *websocket.Conn
+}
+
+type fakePut struct {
+	Path string
+	Body string
+}
+
+func newFakeSysAP(t *testing.T, devices map[string]*Device) *fakeSysAP {
+	return startFakeSysAP(t, devices, httptest.NewServer)
+}
+
+// newFakeTLSSysAP returns a fake serving https and wss with the self-signed certificate of httptest.
+// Failed handshakes aren't logged, tests provoke them.
+func newFakeTLSSysAP(t *testing.T, devices map[string]*Device) *fakeSysAP {
+	return startFakeSysAP(t, devices, func(handler http.Handler) *httptest.Server {
+		server := httptest.NewUnstartedServer(handler)
+		server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		server.StartTLS()
+		return server
+	})
+}
+
+func startFakeSysAP(t *testing.T, devices map[string]*Device, newServer func(http.Handler) *httptest.Server) *fakeSysAP {
+	f := &fakeSysAP{
+		t:                t,
+		putResult:        "OK",
+		virtualDeviceIds: make(map[string]string),
+		conns:            make(chan *websocket.Conn, 10),
+	}
+	f.setDevices(devices)
+	f.server = newServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns a client connected to the fake, which doesn't log. The client of a TLS fake still needs UseTLS.
+func (f *fakeSysAP) client() *Client {
+	logger := log.New(ioutil.Discard, "", 0)
+	host := strings.TrimPrefix(strings.TrimPrefix(f.server.URL, "http://"), "https://")
+	return NewClient(host, "user", "password", nil, nil, logger, 0)
+}
+
+// setDevices replaces the configuration by the devices of testSysAP. The devices are copied, so the test can
+// keep changing its own ones.
+func (f *fakeSysAP) setDevices(devices map[string]*Device) {
+	f.setSysAPs(map[string]map[string]*Device{testSysAP: devices})
+}
+
+// setSysAPs replaces the configuration by the devices of several SysAPs (SysAP UUID -> devices).
+func (f *fakeSysAP) setSysAPs(sysaps map[string]map[string]*Device) {
+	config := make(ApiRestConfigurationGet200ApplicationJsonResponse, len(sysaps))
+	for sysapId, devices := range sysaps {
+		copied := make(map[string]*Device, len(devices))
+		for id, device := range devices {
+			copied[id] = cloneDevice(device)
+		}
+		config[sysapId] = &SysAP{Devices: copied}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.config = config
+}
+
+func (f *fakeSysAP) setPutResult(result string, delay time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.putResult = result
+	f.putDelay = delay
+}
+
+func (f *fakeSysAP) recordedPuts() []fakePut {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]fakePut(nil), f.puts...)
+}
+
+func (f *fakeSysAP) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, ApiPathPrefix)
+	switch {
+	case r.URL.Path == WebSocketPath:
+		f.serveWebSocket(w, r)
+
+	case path == "/api/rest/configuration":
+		f.mutex.Lock()
+		body, err := json2.Marshal(f.config)
+		f.mutex.Unlock()
+		if err != nil {
+			f.t.Errorf("marshal configuration: %s", err)
+		}
+		w.Write(body)
+
+	case strings.HasPrefix(path, "/api/rest/device/"):
+		ids := strings.Split(strings.TrimPrefix(path, "/api/rest/device/"), "/")
+		f.mutex.Lock()
+		device := f.config[ids[0]].Devices[ids[1]]
+		body, _ := json2.Marshal(ApiRestDeviceSysapDeviceGet200ApplicationJsonResponse{ids[0]: {Devices: map[string]*Device{ids[1]: device}}})
+		f.mutex.Unlock()
+		if device == nil {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/api/rest/datapoint/"):
+		body, _ := ioutil.ReadAll(r.Body)
+		f.mutex.Lock()
+		f.puts = append(f.puts, fakePut{Path: path, Body: string(body)})
+		result, delay := f.putResult, f.putDelay
+		f.mutex.Unlock()
+		time.Sleep(delay)
+		sysapId := strings.Split(strings.TrimPrefix(path, "/api/rest/datapoint/"), "/")[0]
+		w.Write([]byte(`{"` + sysapId + `":{"result":"` + result + `"}}`))
+
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/api/rest/virtualdevice/"):
+		body, _ := ioutil.ReadAll(r.Body)
+		serial := strings.Split(strings.TrimPrefix(path, "/api/rest/virtualdevice/"), "/")[1]
+		f.mutex.Lock()
+		f.puts = append(f.puts, fakePut{Path: path, Body: string(body)})
+		deviceId, ok := f.virtualDeviceIds[serial]
+		if !ok {
+			deviceId = "6000" + strings.ToUpper(serial)
+			f.virtualDeviceIds[serial] = deviceId
+		}
+		f.mutex.Unlock()
+		w.Write([]byte(`{"` + testSysAP + `":{"devices":{"` + deviceId + `":{"serial":"` + serial + `"}}}}`))
+
+	default:
+		http.Error(w, `{"error":"unexpected request"}`, http.StatusNotFound)
+	}
+}
+
+func (f *fakeSysAP) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		f.t.Errorf("websocket upgrade: %s", err)
+		return
+	}
+	defer conn.Close()
+	f.conns <- conn
+	for { // discard the keep alive messages of the client until the connection is closed
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// nextConn waits for the next websocket connection of the client.
+func (f *fakeSysAP) nextConn() *websocket.Conn {
+	select {
+	case conn := <-f.conns:
+		return conn
+	case <-time.After(5 * time.Second):
+		f.t.Fatal("client didn't connect to the websocket")
+		return nil
+	}
+}
+
+// sendDatapoints sends a websocket message with the given datapoint values (<device>/<channel>/<datapoint> -> value).
+func sendDatapoints(t *testing.T, conn *websocket.Conn, datapoints map[string]string) {
+	message := WebsocketMessage{testSysAP: {Datapoints: datapoints}}
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatalf("websocket write: %s", err)
+	}
+}
+
+// waitFor polls cond until it is true or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func datapoint(pairingId int, value string) *InOutPut {
+	return &InOutPut{PairingID: intPtr(pairingId), Value: strPtr(value)}
+}
+
+// testDevice returns a device with one channel ch0000.
+func testDevice(name string, functionId FunctionIdType, inputs map[string]*InOutPut, outputs map[string]*InOutPut) *Device {
+	return &Device{
+		DisplayName: strPtr(name),
+		Channels: map[string]*Channel{
+			"ch0000": {
+				DisplayName: strPtr(name),
+				FunctionID:  strPtr(string(functionId)),
+				Inputs:      inputs,
+				Outputs:     outputs,
+			},
+		},
+	}
+}
+
+// testSwitch returns a switch actuator with input idp0000 (AL_SWITCH_ON_OFF) and output odp0000 (AL_INFO_ON_OFF).
+func testSwitch(name string, on string) *Device {
+	return testDevice(name, FID_SWITCH_ACTUATOR,
+		map[string]*InOutPut{"idp0000": datapoint(AL_SWITCH_ON_OFF, on)},
+		map[string]*InOutPut{"odp0000": datapoint(AL_INFO_ON_OFF, on)})
+}
+
+// startClient reads the configuration of the fake and hydrates all units.
+func startClient(t *testing.T, f *fakeSysAP) *Client {
+	c := f.client()
+	if err := c.ReadAndHydradteAllDevices(testContext(t)); err != nil {
+		t.Fatalf("ReadAndHydradteAllDevices: %s", err)
+	}
+	return c
+}
+
+// testContext returns a context which is cancelled when the test ends.
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// startLoop runs the websocket loop of the client until the test ends and returns the connection
+// accepted by the fake.
+func startLoop(t *testing.T, f *fakeSysAP, c *Client) *websocket.Conn {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := c.StartWebSocketLoop(context.Background(), 1000); err != nil {
+			t.Errorf("StartWebSocketLoop: %s", err)
+		}
+	}()
+	t.Cleanup(func() {
+		c.Stop()
+		<-done
+	})
+	return f.nextConn()
+}
+
+// nextEvent returns the next event of the subscription, which passes the filter of the subscription.
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+//go:build ignore
+// +build ignore
+
+// GenerateRegistry writes FunctionIds.go and PairingIds.go from the lists in the registry directory.
+// Run it with "go generate".
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const header = "// Code generated by GenerateRegistry.go from %s; DO NOT EDIT.\n\npackage fahapi\n\n"
+
+func main() {
+	functionIds := readList("registry/functionids.csv", 3)
+	pairingIds := readList("registry/pairingids.csv", 5)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, header, "registry/functionids.csv")
+	out.WriteString("const (\n")
+	for _, row := range functionIds {
+		fmt.Fprintf(&out, "\t%s FunctionIdType = %q\n", row[1], strconv.FormatUint(parseId(row[0]), 16))
+	}
+	out.WriteString(")\n\nvar functionIdInfos = []FunctionIdInfo{\n")
+	for _, row := range functionIds {
+		fmt.Fprintf(&out, "\t{%s, %q, %q},\n", row[1], row[1], row[2])
+	}
+	out.WriteString("}\n")
+	writeSource("FunctionIds.go", out.Bytes())
+
+	out.Reset()
+	fmt.Fprintf(&out, header, "registry/pairingids.csv")
+	out.WriteString("const (\n")
+	for _, row := range pairingIds {
+		fmt.Fprintf(&out, "\t%s = 0x%04X\n", row[1], parseId(row[0]))
+	}
+	out.WriteString(")\n\nvar pairingIdInfos = []PairingIdInfo{\n")
+	for _, row := range pairingIds {
+		fmt.Fprintf(&out, "\t{%s, %q, %q, Direction%s, Value%s},\n", row[1], row[1], row[4], strings.Title(row[2]), strings.Title(row[3]))
+	}
+	out.WriteString("}\n")
+	writeSource("PairingIds.go", out.Bytes())
+}
+
+// readList returns the rows of the list (without the header line) ordered by id.
+func readList(fileName string, fields int) [][]string {
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = fields
+	rows, err := reader.ReadAll()
+	if err != nil {
+		log.Fatalf("%s: %s", fileName, err)
+	}
+	rows = rows[1:]
+	for _, row := range rows {
+		parseId(row[0])
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return parseId(rows[i][0]) < parseId(rows[j][0]) })
+	return rows
+}
+
+func parseId(id string) uint64 {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(id), "0x"), 16, 32)
+	if err != nil {
+		log.Fatalf("illegal id %s: %s", id, err)
+	}
+	return value
+}
+
+func writeSource(fileName string, source []byte) {
+	formatted, err := format.Source(source)
+	if err != nil {
+		log.Fatalf("%s: %s", fileName, err)
+	}
+	if err := ioutil.WriteFile(fileName, formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,193 @@
+package fahapi
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenericDatapoint is one input or output of a GenericUnit.
+type GenericDatapoint struct {
+	DatapointId string // e.g. odp0000
+	PairingId   int    // 0, if the datapoint has no pairing id
+	Name        string // name of the pairing id, e.g. AL_INFO_ON_OFF
+	Value       string // raw value as sent by the SysAP
+	LastChange  time.Time
+	Changed     bool
+}
+
+// GenericUnit is hydrated for all channels with a function id we have no model for. It exposes the raw
+// values of all inputs and outputs keyed by their datapoint id (idp0000, odp0001, ...). Several datapoints
+// can share a pairing id, use InputsByPairing / OutputsByPairing to look them up by pairing id.
+type GenericUnit struct {
+	UnitData
+	FunctionId string
+	Inputs     map[string]*GenericDatapoint
+	Outputs    map[string]*GenericDatapoint
+}
+
+const UntTypeGeneric UnitTypeConst = "Generic"
+
+func CastGEN(u Unit) *GenericUnit {
+	if typeSave, ok := u.(*GenericUnit); ok {
+		return typeSave
+	}
+	log.Print("CastGEN - wrong type\n")
+	return nil
+}
+
+func (gu *GenericUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
+	var outPuts map[string]*InOutPut
+	if channel := gu.GetChannel(); channel != nil {
+		outPuts = channel.Outputs
+	}
+	return updateGenericDatapoints(gu.Outputs, outPuts, outPut)
+}
+
+func (gu *GenericUnit) updateUnitFromInDatapoint(inPut *InOutPut) bool {
+	var inPuts map[string]*InOutPut
+	if channel := gu.GetChannel(); channel != nil {
+		inPuts = channel.Inputs
+	}
+	return updateGenericDatapoints(gu.Inputs, inPuts, inPut)
+}
+
+// updateGenericDatapoints applies the value of inOut, which is one of the datapoints of the channel (inOuts).
+// A value which isn't part of the channel (e.g. applied optimistically by a write) is applied to all
+// datapoints with its pairing id.
+func updateGenericDatapoints(datapoints map[string]*GenericDatapoint, inOuts map[string]*InOutPut, inOut *InOutPut) bool {
+	if inOut.Value == nil {
+		return false
+	}
+	for datapointId, channelInOut := range inOuts {
+		if channelInOut == inOut {
+			return updateGenericDatapoint(datapoints[datapointId], *inOut.Value)
+		}
+	}
+	if inOut.PairingID == nil || *inOut.PairingID == 0 {
+		return false
+	}
+	changed := false
+	for _, datapoint := range datapoints {
+		if datapoint.PairingId == *inOut.PairingID && updateGenericDatapoint(datapoint, *inOut.Value) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func updateGenericDatapoint(datapoint *GenericDatapoint, value string) bool {
+	if datapoint == nil || datapoint.Value == value {
+		return false
+	}
+	datapoint.Value = value
+	datapoint.LastChange = time.Now()
+	datapoint.Changed = true
+	return true
+}
+
+// InputsByPairing returns the inputs with the given pairing id, ordered by datapoint id.
+func (gu *GenericUnit) InputsByPairing(pairingId int) []*GenericDatapoint {
+	return genericDatapointsByPairing(gu.Inputs, pairingId)
+}
+
+// OutputsByPairing returns the outputs with the given pairing id, ordered by datapoint id.
+func (gu *GenericUnit) OutputsByPairing(pairingId int) []*GenericDatapoint {
+	return genericDatapointsByPairing(gu.Outputs, pairingId)
+}
+
+func genericDatapointsByPairing(datapoints map[string]*GenericDatapoint, pairingId int) []*GenericDatapoint {
+	var found []*GenericDatapoint
+	for _, datapointId := range sortedGenericDatapointIds(datapoints) {
+		if datapoints[datapointId].PairingId == pairingId {
+			found = append(found, datapoints[datapointId])
+		}
+	}
+	return found
+}
+
+func sortedGenericDatapointIds(datapoints map[string]*GenericDatapoint) []string {
+	ids := make([]string, 0, len(datapoints))
+	for id := range datapoints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (gu *GenericUnit) resetChanged() {
+	for _, datapoint := range gu.Inputs {
+		datapoint.Changed = false
+	}
+	for _, datapoint := range gu.Outputs {
+		datapoint.Changed = false
+	}
+}
+
+func (gu *GenericUnit) snapshot() Unit {
+	snapshot := *gu
+	snapshot.UnitData = gu.snapshotData()
+	snapshot.Inputs = cloneGenericDatapoints(gu.Inputs)
+	snapshot.Outputs = cloneGenericDatapoints(gu.Outputs)
+	return &snapshot
+}
+
+func cloneGenericDatapoints(datapoints map[string]*GenericDatapoint) map[string]*GenericDatapoint {
+	copied := make(map[string]*GenericDatapoint, len(datapoints))
+	for datapointId, datapoint := range datapoints {
+		datapointCopy := *datapoint
+		copied[datapointId] = &datapointCopy
+	}
+	return copied
+}
+
+func (gu *GenericUnit) String() string {
+	name := ""
+	if channel := gu.GetChannel(); channel != nil && channel.DisplayName != nil {
+		name = strings.TrimSpace(*channel.DisplayName)
+	}
+
+	datapointIds := sortedGenericDatapointIds(gu.Outputs)
+	values := make([]string, len(datapointIds))
+	for i, datapointId := range datapointIds {
+		values[i] = fmt.Sprintf("%s:%s=%s", datapointId, gu.Outputs[datapointId].Name, gu.Outputs[datapointId].Value)
+	}
+
+	return fmt.Sprintf("%s %s (FID %s): %s", gu.prtUnitHead(), name, gu.FunctionId, strings.Join(values, ", "))
+}
+
+func genericFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
+	channel := device.Channels[channelId]
+	gu := GenericUnit{
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeGeneric),
+		Inputs:   genericDatapoints(channel.Inputs),
+		Outputs:  genericDatapoints(channel.Outputs),
+	}
+	if channel.FunctionID != nil {
+		gu.FunctionId = *channel.FunctionID
+	}
+
+	return &gu
+}
+
+func genericDatapoints(inOutPuts map[string]*InOutPut) map[string]*GenericDatapoint {
+	datapoints := make(map[string]*GenericDatapoint, len(inOutPuts))
+	now := time.Now()
+	for datapointId, inOut := range inOutPuts {
+		if inOut == nil {
+			continue
+		}
+		datapoint := &GenericDatapoint{DatapointId: datapointId, LastChange: now}
+		if inOut.PairingID != nil {
+			datapoint.PairingId = *inOut.PairingID
+			datapoint.Name = PairingIdName(*inOut.PairingID)
+		}
+		if inOut.Value != nil {
+			datapoint.Value = *inOut.Value
+		}
+		datapoints[datapointId] = datapoint
+	}
+	return datapoints
+}
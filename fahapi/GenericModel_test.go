@@ -0,0 +1,39 @@
+package fahapi
+
+import "testing"
+
+func TestGenericUnitKeyedByDatapointId(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testDevice("Unknown", FunctionIdType("ffff"),
+			map[string]*InOutPut{"idp0000": datapoint(AL_SWITCH_ON_OFF, "0")},
+			map[string]*InOutPut{
+				"odp0000": datapoint(AL_INFO_ON_OFF, "0"),
+				"odp0001": datapoint(AL_INFO_ON_OFF, "0"),
+				"odp0002": datapoint(0, "a"),
+				"odp0003": datapoint(0, "b"),
+			}),
+	})
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	sendDatapoints(t, conn, map[string]string{
+		"ABB700000001/ch0000/odp0001": "1",
+		"ABB700000001/ch0000/odp0003": "c",
+	})
+	waitFor(t, "update", func() bool { return CastGEN(c.LookupUnit(key)).Outputs["odp0003"].Value == "c" })
+
+	gu := CastGEN(c.LookupUnit(key))
+	want := map[string]string{"odp0000": "0", "odp0001": "1", "odp0002": "a", "odp0003": "c"}
+	for datapointId, value := range want {
+		if datapoint := gu.Outputs[datapointId]; datapoint == nil || datapoint.Value != value {
+			t.Errorf("%s: got %+v, want value %s", datapointId, datapoint, value)
+		}
+	}
+	if outputs := gu.OutputsByPairing(AL_INFO_ON_OFF); len(outputs) != 2 || outputs[0].DatapointId != "odp0000" || outputs[1].DatapointId != "odp0001" {
+		t.Errorf("OutputsByPairing returned %+v", outputs)
+	}
+	if inputs := gu.InputsByPairing(AL_SWITCH_ON_OFF); len(inputs) != 1 || inputs[0].DatapointId != "idp0000" {
+		t.Errorf("InputsByPairing returned %+v", inputs)
+	}
+}
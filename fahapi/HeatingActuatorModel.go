@@ -0,0 +1,141 @@
+package fahapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// HeatingActuatorUnit is one valve channel of a heating, floor heating, radiator or underfloor heating actuator.
+type HeatingActuatorUnit struct {
+	UnitData
+	ValvePosition     int // AL_INFO_VALUE_HEATING, 0..100%
+	ValvePositionSet  bool
+	ActuatingValue    int // AL_ACTUATING_VALUE_HEATING as received from the room temperature controller
+	ActuatingValueSet bool
+	ForceState        int // AL_INFO_FORCE, 0 = not forced
+	ForceStateSet     bool
+	ErrorCode         int // AL_INFO_ERROR, 0 = no fault
+	ErrorCodeSet      bool
+}
+
+const UntTypeHeatingActuator UnitTypeConst = "AcHeatin"
+
+func CastHAU(u Unit) *HeatingActuatorUnit {
+	if typeSave, ok := u.(*HeatingActuatorUnit); ok {
+		return typeSave
+	}
+	log.Print("CastHAU - wrong type\n")
+	return nil
+}
+
+func (hau *HeatingActuatorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
+	changed := false
+
+	switch *outPut.PairingID {
+	case AL_INFO_FORCE: // Indicates the cause of forced operation (0 = not forced)
+		value, ok := hau.decode(outPut)
+		if !ok {
+			break
+		}
+		force := value.Int
+		if force != hau.ForceState {
+			hau.ForceState = force
+			hau.ForceStateSet = true
+			changed = true
+		}
+	case AL_INFO_ERROR: // Indicates load failures / short circuits / etc
+		value, ok := hau.decode(outPut)
+		if !ok {
+			break
+		}
+		errorCode := value.Int
+		if errorCode != hau.ErrorCode {
+			hau.ErrorCode = errorCode
+			hau.ErrorCodeSet = true
+			changed = true
+		}
+	case AL_INFO_VALUE_HEATING: // Reflects the position of the valve
+		value, ok := hau.decode(outPut)
+		if !ok {
+			break
+		}
+		position := value.Int
+		if position != hau.ValvePosition {
+			hau.ValvePosition = position
+			hau.ValvePositionSet = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// the actuating value is an input of the valve, sent by the room temperature controller
+func (hau *HeatingActuatorUnit) updateUnitFromInDatapoint(inPut *InOutPut) bool {
+	if inPut.PairingID == nil || inPut.Value == nil {
+		return false
+	}
+	changed := false
+
+	switch *inPut.PairingID {
+	case AL_ACTUATING_VALUE_HEATING:
+		value, ok := hau.decode(inPut)
+		if !ok {
+			break
+		}
+		actuating := value.Int
+		if actuating != hau.ActuatingValue {
+			hau.ActuatingValue = actuating
+			hau.ActuatingValueSet = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (hau *HeatingActuatorUnit) resetChanged() {
+	hau.ValvePositionSet = false
+	hau.ActuatingValueSet = false
+	hau.ForceStateSet = false
+	hau.ErrorCodeSet = false
+}
+
+// HasFault reports whether the actuator signals an error (AL_INFO_ERROR).
+func (hau *HeatingActuatorUnit) HasFault() bool {
+	return hau.ErrorCode != 0
+}
+
+func (hau *HeatingActuatorUnit) snapshot() Unit {
+	snapshot := *hau
+	snapshot.UnitData = hau.snapshotData()
+	return &snapshot
+}
+
+func (hau *HeatingActuatorUnit) String() string {
+	extra := ""
+	if hau.ForceState != 0 {
+		extra += fmt.Sprintf(" (forced %d)", hau.ForceState)
+	}
+	if hau.HasFault() {
+		extra += fmt.Sprintf(" FAULT %d", hau.ErrorCode)
+	}
+	name := strings.TrimSpace(*hau.GetChannel().DisplayName)
+	return fmt.Sprintf("%s %s: valve %3d%% (requested %3d%%)%s", hau.prtUnitHead(), name, hau.ValvePosition, hau.ActuatingValue, extra)
+}
+
+func heatingActuatorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
+	hau := HeatingActuatorUnit{
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeHeatingActuator),
+	}
+
+	for _, inOut := range device.Channels[channelId].Outputs {
+		hau.updateUnitFromOutDatapoint(inOut)
+	}
+	for _, inOut := range device.Channels[channelId].Inputs {
+		hau.updateUnitFromInDatapoint(inOut)
+	}
+
+	return &hau
+}
@@ -0,0 +1,49 @@
+package fahapi
+
+import "testing"
+
+func TestHeatingActuatorRoundsLikeController(t *testing.T) {
+	for _, raw := range []string{"0", "45.4", "45.6", "99.5"} {
+		f := newFakeSysAP(t, map[string]*Device{
+			"ABB700000001": testDevice("Valve", FID_HEATING_ACTUATOR,
+				map[string]*InOutPut{"idp0000": datapoint(AL_ACTUATING_VALUE_HEATING, raw)},
+				map[string]*InOutPut{"odp0000": datapoint(AL_INFO_VALUE_HEATING, raw)}),
+			"ABB700000002": testDevice("RTC", FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITHOUT_FAN, nil,
+				map[string]*InOutPut{"odp0000": datapoint(AL_ACTUATING_VALUE_HEATING, raw)}),
+		})
+		c := startClient(t, f)
+
+		valve := CastHAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+		rtc := CastRTC(c.LookupChannelUnit(testSysAP, "ABB700000002", "ch0000"))
+		if valve.ActuatingValue != rtc.Capacity || valve.ValvePosition != rtc.Capacity {
+			t.Errorf("%s: valve actuating %d / position %d, controller %d", raw, valve.ActuatingValue, valve.ValvePosition, rtc.Capacity)
+		}
+	}
+}
+
+func TestHeatingActuatorHydration(t *testing.T) {
+	functionIds := []FunctionIdType{FID_HEATING_ACTUATOR, FID_FLOOR_HEATING_ACTUATOR, FID_RADIATOR_ACTUATOR, FID_UNDERFLOOR_HEATING}
+	for _, functionId := range functionIds {
+		info, _ := LookupFunctionId(functionId)
+		t.Run(info.Name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{
+				"ABB700000001": testDevice("Valve", functionId,
+					map[string]*InOutPut{"idp0000": datapoint(AL_ACTUATING_VALUE_HEATING, "60")},
+					map[string]*InOutPut{
+						"odp0000": datapoint(AL_INFO_VALUE_HEATING, "55"),
+						"odp0001": datapoint(AL_INFO_FORCE, "2"),
+						"odp0002": datapoint(AL_INFO_ERROR, "1"),
+					}),
+			})
+			c := startClient(t, f)
+
+			valve, ok := c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000").(*HeatingActuatorUnit)
+			if !ok {
+				t.Fatalf("got %T, want *HeatingActuatorUnit", c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+			}
+			if valve.ActuatingValue != 60 || valve.ValvePosition != 55 || valve.ForceState != 2 || valve.ErrorCode != 1 {
+				t.Errorf("actuating %d, position %d, force %d, error %d", valve.ActuatingValue, valve.ValvePosition, valve.ForceState, valve.ErrorCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package fahapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type MovementDetectorUnit struct {
+	UnitData
+	Motion        bool
+	MotionSet     bool
+	Presence      bool
+	PresenceSet   bool
+	TimedStart    bool
+	TimedStartSet bool
+	Luminance     float64
+	LuminanceSet  bool
+	On            bool // AL_INFO_ON_OFF of the actuator variant (FID_MOVEMENT_DETECTOR_ACTUATOR)
+	OnSet         bool
+	LastMotion    time.Time // time of the last websocket update starting motion or presence, zero if not seen since start
+}
+
+const UntTypeMovementDetector UnitTypeConst = "SeMotion"
+
+func CastMDU(u Unit) *MovementDetectorUnit {
+	if typeSave, ok := u.(*MovementDetectorUnit); ok {
+		return typeSave
+	}
+	log.Print("CastMDU - wrong type\n")
+	return nil
+}
+
+func (md *MovementDetectorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
+	changed := false
+
+	switch *outPut.PairingID {
+	case AL_TIMED_START_STOP: // Starts or stops the timer of a staircase light or movement detector
+		value, ok := md.decode(outPut)
+		if !ok {
+			break
+		}
+		timedStart := value.Bool
+		if timedStart != md.TimedStart {
+			md.TimedStart = timedStart
+			md.TimedStartSet = true
+			changed = true
+		}
+	case AL_TIMED_MOVEMENT: // Motion detected
+		value, ok := md.decode(outPut)
+		if !ok {
+			break
+		}
+		motion := value.Bool
+		if motion != md.Motion {
+			md.Motion = motion
+			md.MotionSet = true
+			changed = true
+		}
+	case AL_TIMED_PRESENCE: // Presence detected
+		value, ok := md.decode(outPut)
+		if !ok {
+			break
+		}
+		presence := value.Bool
+		if presence != md.Presence {
+			md.Presence = presence
+			md.PresenceSet = true
+			changed = true
+		}
+	case AL_BRIGHTNESS_LEVEL:
+		value, ok := md.decode(outPut)
+		if !ok {
+			break
+		}
+		luminance := value.Float
+		if luminanceChanged(md.Luminance, luminance) {
+			md.Luminance = luminance
+			md.LuminanceSet = true
+			changed = true
+		}
+	case AL_INFO_ON_OFF: // switching state of the actuator variant
+		value, ok := md.decode(outPut)
+		if !ok {
+			break
+		}
+		on := value.Bool
+		if on != md.On {
+			md.On = on
+			md.OnSet = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// motion or presence started with this websocket update
+func (md *MovementDetectorUnit) updateUnitFromTransition(outPut *InOutPut) {
+	switch *outPut.PairingID {
+	case AL_TIMED_MOVEMENT:
+		if md.Motion {
+			md.LastMotion = md.LastUpdate
+		}
+	case AL_TIMED_PRESENCE:
+		if md.Presence {
+			md.LastMotion = md.LastUpdate
+		}
+	}
+}
+
+func (md *MovementDetectorUnit) resetChanged() {
+	md.MotionSet = false
+	md.PresenceSet = false
+	md.TimedStartSet = false
+	md.LuminanceSet = false
+	md.OnSet = false
+}
+
+func (md *MovementDetectorUnit) snapshot() Unit {
+	snapshot := *md
+	snapshot.UnitData = md.snapshotData()
+	return &snapshot
+}
+
+func (md *MovementDetectorUnit) String() string {
+	motion := "no motion"
+	if md.Motion || md.Presence {
+		motion = "MOTION   "
+	}
+	timed := ""
+	if md.TimedStart {
+		timed = " (timer running)"
+	}
+	if md.On {
+		timed += " ON"
+	}
+	last := ""
+	if !md.LastMotion.IsZero() {
+		last = " last " + md.LastMotion.Format("15:04:05")
+	}
+	name := strings.TrimSpace(*md.GetChannel().DisplayName)
+	return fmt.Sprintf("%s %s: %s %.2f lux%s%s", md.prtUnitHead(), name, motion, md.Luminance, timed, last)
+}
+
+func movementDetectorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
+	md := MovementDetectorUnit{
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeMovementDetector),
+	}
+
+	for _, inOut := range device.Channels[channelId].Outputs {
+		md.updateUnitFromOutDatapoint(inOut)
+	}
+
+	return &md
+}
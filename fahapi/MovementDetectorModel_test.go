@@ -0,0 +1,72 @@
+package fahapi
+
+import (
+	"testing"
+	"time"
+)
+
+func testMovementDetector(motion, lux string) *Device {
+	return testDevice("Motion", FID_MOVEMENT_DETECTOR, nil, map[string]*InOutPut{
+		"odp0000": datapoint(AL_TIMED_MOVEMENT, motion),
+		"odp0001": datapoint(AL_BRIGHTNESS_LEVEL, lux),
+	})
+}
+
+func TestMovementDetectorLastMotion(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testMovementDetector("1", "0")})
+	c := startClient(t, f)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	md := CastMDU(c.LookupUnit(key))
+	if !md.Motion || !md.LastMotion.IsZero() {
+		t.Errorf("hydrated motion %v, last motion %s, want motion without time", md.Motion, md.LastMotion)
+	}
+	if md.Luminance != 0 {
+		t.Errorf("luminance %f, want 0", md.Luminance)
+	}
+
+	conn := startLoop(t, f, c)
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "0", "ABB700000001/ch0000/odp0001": "120"})
+	waitFor(t, "motion ended", func() bool { return !CastMDU(c.LookupUnit(key)).Motion })
+	if md = CastMDU(c.LookupUnit(key)); !md.LastMotion.IsZero() || md.Luminance != 120 {
+		t.Errorf("after motion ended: last motion %s, luminance %f", md.LastMotion, md.Luminance)
+	}
+
+	sent := time.Now()
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "1", "ABB700000001/ch0000/odp0001": "0"})
+	waitFor(t, "motion started", func() bool { return CastMDU(c.LookupUnit(key)).Motion })
+	md = CastMDU(c.LookupUnit(key))
+	if md.LastMotion.Before(sent) || md.LastMotion.After(md.LastUpdate) {
+		t.Errorf("last motion %s, want time of the update after %s", md.LastMotion, sent)
+	}
+	if md.Luminance != 0 {
+		t.Errorf("luminance %f, want 0", md.Luminance)
+	}
+	lastMotion := md.LastMotion
+
+	// motion seen again by a resync doesn't count, its time is unknown
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "0"})
+	waitFor(t, "motion ended", func() bool { return !CastMDU(c.LookupUnit(key)).Motion })
+	f.setDevices(map[string]*Device{"ABB700000001": testMovementDetector("1", "0")})
+	conn.Close()
+	f.nextConn()
+	waitFor(t, "motion resynced", func() bool { return CastMDU(c.LookupUnit(key)).Motion })
+	if md = CastMDU(c.LookupUnit(key)); !md.LastMotion.Equal(lastMotion) {
+		t.Errorf("resync changed last motion to %s", md.LastMotion)
+	}
+}
+
+func TestMovementDetectorActuator(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testDevice("Motion", FID_MOVEMENT_DETECTOR_ACTUATOR, nil, map[string]*InOutPut{
+			"odp0000": datapoint(AL_TIMED_MOVEMENT, "0"),
+			"odp0001": datapoint(AL_INFO_ON_OFF, "1"),
+		}),
+	})
+	c := startClient(t, f)
+
+	md, ok := c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000").(*MovementDetectorUnit)
+	if !ok || !md.On {
+		t.Errorf("got %#v, want a switched on movement detector", c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+	}
+}
@@ -0,0 +1,119 @@
+package fahapi
+
+import "fmt"
+
+// The function ids (FID_...) and pairing ids (AL_...) are generated from registry/functionids.csv and
+// registry/pairingids.csv. These lists are a hand-picked subset of the ones published in the free@home local
+// API documentation, covering the ids the models and virtual devices use. Lookups of other ids fail.
+
+//go:generate go run GenerateRegistry.go
+
+// FunctionIdType is the function id of a channel, as hex string without leading zeros (e.g. "1d0").
+type FunctionIdType string
+
+// FunctionIdInfo describes a function id.
+type FunctionIdInfo struct {
+	Id          FunctionIdType
+	Name        string
+	Description string
+}
+
+// Direction tells whether a datapoint with this pairing id is written to a device (input), reported by a
+// device (output) or both (sent by sensors to the inputs of actuators).
+type Direction string
+
+const (
+	DirectionInput  Direction = "input"
+	DirectionOutput Direction = "output"
+	DirectionBoth   Direction = "both"
+)
+
+// ValueType is the type of the (string) values of a datapoint.
+type ValueType string
+
+const (
+	ValueBool    ValueType = "bool"    // "0" / "1"
+	ValuePercent ValueType = "percent" // 0..100
+	ValueFloat   ValueType = "float"   // e.g. temperatures in °C
+	ValueInt     ValueType = "int"     // counters, levels, error codes
+	ValueEnum    ValueType = "enum"    // small set of integer codes, see the description
+)
+
+// PairingIdInfo describes a pairing id.
+type PairingIdInfo struct {
+	Id          int
+	Name        string
+	Description string
+	Direction   Direction
+	ValueType   ValueType
+}
+
+var functionIdById, functionIdByName = indexFunctionIds(functionIdInfos)
+
+func indexFunctionIds(infos []FunctionIdInfo) (map[FunctionIdType]FunctionIdInfo, map[string]FunctionIdInfo) {
+	byId := make(map[FunctionIdType]FunctionIdInfo, len(infos))
+	byName := make(map[string]FunctionIdInfo, len(infos))
+	for _, info := range infos {
+		byId[info.Id] = info
+		byName[info.Name] = info
+	}
+	return byId, byName
+}
+
+// LookupFunctionId returns the description of the function id.
+func LookupFunctionId(functionId FunctionIdType) (FunctionIdInfo, bool) {
+	info, ok := functionIdById[functionId]
+	return info, ok
+}
+
+// LookupFunctionIdByName returns the description of the function id with the given name (e.g. FID_SWITCH_ACTUATOR).
+func LookupFunctionIdByName(name string) (FunctionIdInfo, bool) {
+	info, ok := functionIdByName[name]
+	return info, ok
+}
+
+// FunctionIds returns the descriptions of all known function ids, ordered by id.
+func FunctionIds() []FunctionIdInfo {
+	infos := make([]FunctionIdInfo, len(functionIdInfos))
+	copy(infos, functionIdInfos)
+	return infos
+}
+
+var pairingIdById, pairingIdByName = indexPairingIds(pairingIdInfos)
+
+func indexPairingIds(infos []PairingIdInfo) (map[int]PairingIdInfo, map[string]PairingIdInfo) {
+	byId := make(map[int]PairingIdInfo, len(infos))
+	byName := make(map[string]PairingIdInfo, len(infos))
+	for _, info := range infos {
+		byId[info.Id] = info
+		byName[info.Name] = info
+	}
+	return byId, byName
+}
+
+// LookupPairingId returns the description of the pairing id.
+func LookupPairingId(pairingId int) (PairingIdInfo, bool) {
+	info, ok := pairingIdById[pairingId]
+	return info, ok
+}
+
+// LookupPairingIdByName returns the description of the pairing id with the given name (e.g. AL_INFO_ON_OFF).
+func LookupPairingIdByName(name string) (PairingIdInfo, bool) {
+	info, ok := pairingIdByName[name]
+	return info, ok
+}
+
+// PairingIds returns the descriptions of all known pairing ids, ordered by id.
+func PairingIds() []PairingIdInfo {
+	infos := make([]PairingIdInfo, len(pairingIdInfos))
+	copy(infos, pairingIdInfos)
+	return infos
+}
+
+// PairingIdName returns the name of the pairing id (e.g. AL_INFO_ON_OFF) or its hex value, if it is unknown.
+func PairingIdName(pairingId int) string {
+	if info, ok := pairingIdById[pairingId]; ok {
+		return info.Name
+	}
+	return fmt.Sprintf("0x%04X", pairingId)
+}
@@ -0,0 +1,82 @@
+package fahapi
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRegistryIsConsistent(t *testing.T) {
+	for i, info := range PairingIds() {
+		if byId, ok := LookupPairingId(info.Id); !ok || byId.Name != info.Name {
+			t.Errorf("pairing id 0x%04X is listed more than once", info.Id)
+		}
+		if byName, ok := LookupPairingIdByName(info.Name); !ok || byName.Id != info.Id {
+			t.Errorf("pairing id name %s is listed more than once", info.Name)
+		}
+		if i > 0 && PairingIds()[i-1].Id >= info.Id {
+			t.Errorf("pairing id %s isn't ordered by id", info.Name)
+		}
+		switch info.Direction {
+		case DirectionInput, DirectionOutput, DirectionBoth:
+		default:
+			t.Errorf("pairing id %s has illegal direction %q", info.Name, info.Direction)
+		}
+	}
+	for _, info := range FunctionIds() {
+		if byId, ok := LookupFunctionId(info.Id); !ok || byId.Name != info.Name {
+			t.Errorf("function id %s is listed more than once", info.Id)
+		}
+		if byName, ok := LookupFunctionIdByName(info.Name); !ok || byName.Id != info.Id {
+			t.Errorf("function id name %s is listed more than once", info.Name)
+		}
+	}
+}
+
+// readRegistryList returns the rows of a list in the registry directory without the header.
+func readRegistryList(t *testing.T, fileName string) [][]string {
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("%s: %s", fileName, err)
+	}
+	return rows[1:]
+}
+
+func TestRegistryMatchesSource(t *testing.T) {
+	functionIds := readRegistryList(t, "registry/functionids.csv")
+	if len(functionIds) != len(FunctionIds()) {
+		t.Errorf("registry/functionids.csv has %d function ids, FunctionIds() %d: run go generate", len(functionIds), len(FunctionIds()))
+	}
+	for _, row := range functionIds {
+		id, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(row[0]), "0x"), 16, 32)
+		if err != nil {
+			t.Fatalf("illegal function id %s", row[0])
+		}
+		if info, ok := LookupFunctionIdByName(row[1]); !ok || info.Id != FunctionIdType(strconv.FormatUint(id, 16)) {
+			t.Errorf("function id %s %s is missing or differs: %+v", row[0], row[1], info)
+		}
+	}
+
+	pairingIds := readRegistryList(t, "registry/pairingids.csv")
+	if len(pairingIds) != len(PairingIds()) {
+		t.Errorf("registry/pairingids.csv has %d pairing ids, PairingIds() %d: run go generate", len(pairingIds), len(PairingIds()))
+	}
+	for _, row := range pairingIds {
+		id, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(row[0]), "0x"), 16, 32)
+		if err != nil {
+			t.Fatalf("illegal pairing id %s", row[0])
+		}
+		if info, ok := LookupPairingIdByName(row[1]); !ok || info.Id != int(id) {
+			t.Errorf("pairing id %s %s is missing or differs: %+v", row[0], row[1], info)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package fahapi
+
+import (
+	"context"
+	"fmt"
+)
+
+// resync re-reads the configuration of all SysAPs and reconciles FreeDevices and UnitMap with it.
+// It is used after a websocket reconnect, because updates sent while we were disconnected are lost.
+// Added devices are hydrated, removed devices are dropped and changed values are applied to the
+// existing units. The keys of all new or changed units are returned.
+func (c *Client) resync(ctx context.Context) ([]string, error) {
+	configResult, err := c.GetConfiguration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't resync f@h configuration: %w", err)
+	}
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	changedMap := make(map[string]bool)
+	withEvents := c.hasSubscribers()
+	addedDevices := make(map[string]map[string]bool)
+
+	for sysapId, sysap := range configResult {
+		if sysap.Devices == nil {
+			sysap.Devices = make(map[string]*Device)
+		}
+		compactDevices(sysap.Devices)
+		oldDevices := c.freeDevices[sysapId]
+
+		for deviceId, newDevice := range sysap.Devices {
+			oldDevice, ok := oldDevices[deviceId]
+			if ok && !deviceStructureChanged(oldDevice, newDevice) {
+				// keep the old device object, the units point to it
+				sysap.Devices[deviceId] = oldDevice
+				for _, key := range c.resyncDeviceValues(sysapId, deviceId, oldDevice, newDevice) {
+					changedMap[key] = true
+				}
+				continue
+			}
+
+			if ok {
+				c.queueEvent(&DeviceMetadataChangedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, Old: cloneDevice(oldDevice), New: cloneDevice(newDevice)})
+				// the units are hydrated again below, keep their old state for the UnitChangedEvent
+				for channelId := range oldDevice.Channels {
+					key := getUnitMapKey(sysapId, deviceId, channelId)
+					if unit, ok := c.unitMap[key]; ok {
+						if withEvents {
+							c.rememberUnitBeforeChange(key, unit.snapshot())
+						}
+						delete(c.unitMap, key)
+					}
+				}
+			} else {
+				if addedDevices[sysapId] == nil {
+					addedDevices[sysapId] = make(map[string]bool)
+				}
+				addedDevices[sysapId][deviceId] = true
+			}
+			if c.logLevel > 0 {
+				c.logger.Printf("resync: device %s on SysAP %s is new or has changed\n", deviceId, sysapId)
+			}
+		}
+
+		for deviceId, oldDevice := range oldDevices {
+			if _, ok := sysap.Devices[deviceId]; !ok {
+				if c.logLevel > 0 {
+					c.logger.Printf("resync: device %s on SysAP %s was removed\n", deviceId, sysapId)
+				}
+				c.removeDevice(sysapId, deviceId, oldDevice)
+			}
+		}
+	}
+
+	for sysapId, oldDevices := range c.freeDevices {
+		if _, ok := configResult[sysapId]; !ok {
+			for deviceId, oldDevice := range oldDevices {
+				c.removeDevice(sysapId, deviceId, oldDevice)
+			}
+		}
+	}
+
+	c.sysAPConfiguration = configResult
+	c.freeDevices = make(map[string]map[string]*Device, len(configResult))
+	for sysapId, sysap := range configResult {
+		c.freeDevices[sysapId] = sysap.Devices
+	}
+
+	// the floorplan could have changed too (e.g. a renamed room)
+	for key, unit := range c.unitMap {
+		unitData := unit.GetUnitData()
+		floor, room := c.getFloorRoom(unitData.SysApId, unitData.Device, unitData.GetChannel())
+		if floor != unitData.Floor || room != unitData.Room {
+			unitData.Floor = floor
+			unitData.Room = room
+			changedMap[key] = true
+		}
+	}
+
+	// hydrate the new and changed devices, after the new floorplan is in place
+	for sysapId, sysap := range configResult {
+		for deviceId, device := range sysap.Devices {
+			for channelId := range device.Channels {
+				if c.getUnit(sysapId, deviceId, channelId) != nil {
+					continue
+				}
+				if unit := c.hydrateChannel(sysapId, deviceId, device, channelId); unit != nil {
+					c.queueUnitErrors(unit.GetUnitData())
+					key := unit.getUnitMapKey()
+					c.unitMap[key] = unit
+					changedMap[key] = true
+					if withEvents {
+						c.rememberUnitBeforeChange(key, nil)
+					}
+				}
+			}
+			if addedDevices[sysapId][deviceId] {
+				c.queueEvent(&DeviceAddedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, Device: cloneDevice(device)})
+			}
+		}
+	}
+
+	// forget the old state of units which vanished with a changed device
+	for key := range c.unitsBeforeChange {
+		if _, ok := c.unitMap[key]; !ok {
+			delete(c.unitsBeforeChange, key)
+		}
+	}
+
+	changedKeys := make([]string, 0, len(changedMap))
+	for k := range changedMap {
+		changedKeys = append(changedKeys, k)
+	}
+
+	return changedKeys, nil
+}
+
+// resyncDeviceValues copies all output values of newDevice into oldDevice and updates the units.
+func (c *Client) resyncDeviceValues(sysapId, deviceId string, oldDevice, newDevice *Device) []string {
+	var changedKeys []string
+
+	oldDevice.Unresponsive = newDevice.Unresponsive
+	for channelId, newChannel := range newDevice.Channels {
+		oldChannel := oldDevice.Channels[channelId]
+		for datapointId, newOutPoint := range newChannel.Outputs {
+			outPoint := oldChannel.Outputs[datapointId]
+			if newOutPoint.Value == nil || (outPoint.Value != nil && *outPoint.Value == *newOutPoint.Value) {
+				continue
+			}
+			updateDeviceDatapoint(outPoint, *newOutPoint.Value)
+			if key, changed := c.reHydrateUnitValue(sysapId, deviceId, channelId, outPoint); changed {
+				changedKeys = append(changedKeys, key)
+			}
+			c.matchPendingWrites(sysapId, deviceId, channelId, outPoint)
+		}
+		for datapointId, newInPoint := range newChannel.Inputs {
+			inPoint, ok := oldChannel.Inputs[datapointId]
+			if !ok || inPoint == nil || newInPoint == nil || newInPoint.Value == nil || (inPoint.Value != nil && *inPoint.Value == *newInPoint.Value) {
+				continue
+			}
+			updateDeviceDatapoint(inPoint, *newInPoint.Value)
+			if key, changed := c.reHydrateUnitInput(sysapId, deviceId, channelId, inPoint); changed {
+				changedKeys = append(changedKeys, key)
+			}
+		}
+	}
+
+	return changedKeys
+}
+
+// removeDevice drops the units of a device, which is no longer known to the SysAP, and queues a DeviceRemovedEvent.
+// Removing the device itself from freeDevices is up to the caller.
+func (c *Client) removeDevice(sysapId, deviceId string, device *Device) {
+	var unitKeys []string
+	for channelId := range device.Channels {
+		key := getUnitMapKey(sysapId, deviceId, channelId)
+		if _, ok := c.unitMap[key]; ok {
+			unitKeys = append(unitKeys, key)
+			delete(c.unitMap, key)
+		}
+		delete(c.unitsBeforeChange, key)
+	}
+	c.queueEvent(&DeviceRemovedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, UnitKeys: unitKeys})
+}
+
+// deviceStructureChanged reports whether anything but the datapoint values differs,
+// so that the units of the device have to be hydrated again.
+func deviceStructureChanged(old, new *Device) bool {
+	if !strPtrEqual(old.DisplayName, new.DisplayName) || !strPtrEqual(old.Floor, new.Floor) ||
+		!strPtrEqual(old.Room, new.Room) || !strPtrEqual(old.NativeId, new.NativeId) ||
+		len(old.Channels) != len(new.Channels) {
+		return true
+	}
+	for channelId, newChannel := range new.Channels {
+		oldChannel, ok := old.Channels[channelId]
+		if !ok {
+			return true
+		}
+		if !strPtrEqual(oldChannel.DisplayName, newChannel.DisplayName) || !strPtrEqual(oldChannel.FunctionID, newChannel.FunctionID) ||
+			!strPtrEqual(oldChannel.Floor, newChannel.Floor) || !strPtrEqual(oldChannel.Room, newChannel.Room) ||
+			!sameDatapointIds(oldChannel.Inputs, newChannel.Inputs) || !sameDatapointIds(oldChannel.Outputs, newChannel.Outputs) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDatapointIds(a, b map[string]*InOutPut) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func strPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
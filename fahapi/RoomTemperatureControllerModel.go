@@ -1,24 +1,65 @@
 package fahapi
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 )
 
 type RoomTemperatureControllerUnit struct {
 	UnitData
-	ActualDegree    float64
-	TargetDegree    float64
-	Active          int
-	Capacity        int
-	ActualDegreeSet bool
-	TargetDegreeSet bool
-	ActiveSet       bool
-	CapacitySet     bool
+	ActualDegree        float64
+	TargetDegree        float64
+	RelativeSetPoint    float64 // AL_RELATIVE_SET_POINT_TEMPERATURE, offset to the base set point in K
+	Active              int     // heating active
+	Capacity            int     // heating valve 0..100%
+	CoolingActive       bool
+	CoolingCapacity     int // cooling valve 0..100%
+	HeatingDemand       bool
+	CoolingDemand       bool
+	FanLevel            int  // AL_FAN_COIL_LEVEL, current stage of the fan coil
+	FanManual           bool // fan stage set manually instead of by the controller
+	FanStageHeating     int  // fan stage requested by the controller for heating
+	FanStageCooling     int  // fan stage requested by the controller for cooling
+	On                  bool // off means protection mode
+	Eco                 bool
+	StateIndication     int // AL_STATE_INDICATION as sent by the SysAP
+	ErrorCode           int // AL_INFO_ERROR, 0 = no fault
+	ActualDegreeSet     bool
+	TargetDegreeSet     bool
+	RelativeSetPointSet bool
+	ActiveSet           bool
+	CapacitySet         bool
+	CoolingActiveSet    bool
+	CoolingCapacitySet  bool
+	HeatingDemandSet    bool
+	CoolingDemandSet    bool
+	FanLevelSet         bool
+	FanManualSet        bool
+	FanStageHeatingSet  bool
+	FanStageCoolingSet  bool
+	OnSet               bool
+	EcoSet              bool
+	StateIndicationSet  bool
+	ErrorCodeSet        bool
 }
 
+// range of the set point temperature accepted by the controllers
+const (
+	MinTargetDegree = 7.0
+	MaxTargetDegree = 35.0
+)
+
+// range of the offset to the base set point in K
+const (
+	MinRelativeSetPoint = -5.0
+	MaxRelativeSetPoint = 5.0
+)
+
+// highest stage of a fan coil, 0 is off
+const MaxFanStage = 3
+
 const UntTypeRoomTemperatureController UnitTypeConst = "CoRoTemp"
 
 func CastRTC(u Unit) *RoomTemperatureControllerUnit {
@@ -32,55 +73,176 @@ func CastRTC(u Unit) *RoomTemperatureControllerUnit {
 func (rtc *RoomTemperatureControllerUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
 	changed := false
 	switch *outPut.PairingID {
-	case 0x0030: // AL_ACTUATING_VALUE_HEATING (Determines the through flow volume of the control valve)
-		capacity, _ := strconv.Atoi(*outPut.Value)
+	case AL_ACTUATING_VALUE_HEATING: // Determines the through flow volume of the control valve
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		capacity := value.Int
 		if capacity != rtc.Capacity {
 			rtc.Capacity = capacity
 			rtc.CapacitySet = true
 			rtc.LastUpdate = time.Now()
 			changed = true
 		}
-	case 0x0031: // AL_FAN_COIL_LEVEL
-	case 0x0032: // AL_ACTUATING_VALUE_COOLING (Determines the through flow volume of the control valve)
-	case 0x0033: // AL_SET_POINT_TEMPERATURE (Defines the displayed set point Temperature of the system)
-		target, _ := strconv.ParseFloat(*outPut.Value, 64)
+	case AL_FAN_COIL_LEVEL:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		level := value.Int
+		if level != rtc.FanLevel {
+			rtc.FanLevel = level
+			rtc.FanLevelSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_ACTUATING_VALUE_COOLING: // Determines the through flow volume of the control valve
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		capacity := value.Int
+		if capacity != rtc.CoolingCapacity {
+			rtc.CoolingCapacity = capacity
+			rtc.CoolingCapacitySet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_SET_POINT_TEMPERATURE: // Defines the displayed set point Temperature of the system
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		target := value.Float
 		if target != rtc.TargetDegree {
 			rtc.TargetDegree = target
 			rtc.TargetDegreeSet = true
 			rtc.LastUpdate = time.Now()
 			changed = true
 		}
-	case 0x0034: // AL_RELATIVE_SET_POINT_TEMPERATURE
-	case 0x0036: // AL_STATE_INDICATION (states: on/off heating/cooling; eco/comfort; frost/not frost)
-	case 0x0037: // AL_FAN_MANUAL_ON_OFF
-	case 0x0038: // AL_CONTROLLER_ON_OFF (Switches controller on or off. Off means protection mode)
-	case 0x0039: // AL_RELATIVE_SET_POINT_REQUEST
-	case 0x003A: // AL_ECO_ON_OFF
-	case 0x0040: // AL_FAN_STAGE_REQUEST
-	case 0x0042: // AL_CONTROLLER_ON_OFF_REQUEST
-	case 0x0111: // AL_INFO_ERROR
-		if *outPut.Value != "0" {
-			log.Fatalf("Device Error %s", *outPut.Value)
+	case AL_RELATIVE_SET_POINT_TEMPERATURE:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		relative := value.Float
+		if relative != rtc.RelativeSetPoint {
+			rtc.RelativeSetPoint = relative
+			rtc.RelativeSetPointSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_STATE_INDICATION: // states: on/off heating/cooling; eco/comfort; frost/not frost
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		state := value.Int
+		if state != rtc.StateIndication {
+			rtc.StateIndication = state
+			rtc.StateIndicationSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
 		}
-	case 0x0130: // AL_MEASURED_TEMPERATURE
-		actual, _ := strconv.ParseFloat(*outPut.Value, 64)
+	case AL_FAN_MANUAL_ON_OFF:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		manual := value.Bool
+		if manual != rtc.FanManual {
+			rtc.FanManual = manual
+			rtc.FanManualSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_CONTROLLER_ON_OFF: // Switches controller on or off. Off means protection mode
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		on := value.Bool
+		if on != rtc.On {
+			rtc.On = on
+			rtc.OnSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_RELATIVE_SET_POINT_REQUEST:
+	case AL_ECO_ON_OFF:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		eco := value.Bool
+		if eco != rtc.Eco {
+			rtc.Eco = eco
+			rtc.EcoSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_FAN_STAGE_REQUEST:
+	case AL_CONTROLLER_ON_OFF_REQUEST:
+	case AL_INFO_ERROR:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		errorCode := value.Int
+		if errorCode != rtc.ErrorCode {
+			rtc.ErrorCode = errorCode
+			rtc.ErrorCodeSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_MEASURED_TEMPERATURE:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		actual := value.Float
 		if actual != rtc.ActualDegree {
 			rtc.ActualDegree = actual
 			rtc.ActualDegreeSet = true
 			rtc.LastUpdate = time.Now()
 			changed = true
 		}
-	case 0x0131: // AL_INFO_VALUE_HEATING
-	case 0x0136: // AL_ACTUATING_FAN_STAGE_HEATING
-	case 0x0143: // AL_ACTUATING_VALUE_ADD_HEATING
-	case 0x0144: // AL_ACTUATING_VALUE_ADD_COOLING
-	case 0x0147: // AL_ACTUATING_FAN_STAGE_COOLING
-	case 0x014B: // AL_HEATING_ACTIVE
+	case AL_INFO_VALUE_HEATING:
+	case AL_ACTUATING_FAN_STAGE_HEATING:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		stage := value.Int
+		if stage != rtc.FanStageHeating {
+			rtc.FanStageHeating = stage
+			rtc.FanStageHeatingSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_ACTUATING_VALUE_ADD_HEATING:
+	case AL_ACTUATING_VALUE_ADD_COOLING:
+	case AL_ACTUATING_FAN_STAGE_COOLING:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		stage := value.Int
+		if stage != rtc.FanStageCooling {
+			rtc.FanStageCooling = stage
+			rtc.FanStageCoolingSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_HEATING_ACTIVE:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
 		var active int
-		if *outPut.Value == "1" {
+		if value.Bool {
 			active = 1
-		} else {
-			active = 0
 		}
 		if active != rtc.Active {
 			rtc.Active = active
@@ -88,9 +250,42 @@ func (rtc *RoomTemperatureControllerUnit) updateUnitFromOutDatapoint(outPut *InO
 			rtc.LastUpdate = time.Now()
 			changed = true
 		}
-	case 0x014C: // AL_COOLING_ACTIVE
-	case 0x014D: // AL_HEATING_DEMAND
-	case 0x014E: // AL_COOLING_DEMAND
+	case AL_COOLING_ACTIVE:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		active := value.Bool
+		if active != rtc.CoolingActive {
+			rtc.CoolingActive = active
+			rtc.CoolingActiveSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_HEATING_DEMAND:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		demand := value.Bool
+		if demand != rtc.HeatingDemand {
+			rtc.HeatingDemand = demand
+			rtc.HeatingDemandSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
+	case AL_COOLING_DEMAND:
+		value, ok := rtc.decode(outPut)
+		if !ok {
+			break
+		}
+		demand := value.Bool
+		if demand != rtc.CoolingDemand {
+			rtc.CoolingDemand = demand
+			rtc.CoolingDemandSet = true
+			rtc.LastUpdate = time.Now()
+			changed = true
+		}
 	}
 
 	return changed
@@ -101,6 +296,69 @@ func (rtc *RoomTemperatureControllerUnit) resetChanged() {
 	rtc.ActualDegreeSet = false
 	rtc.TargetDegreeSet = false
 	rtc.CapacitySet = false
+	rtc.RelativeSetPointSet = false
+	rtc.CoolingActiveSet = false
+	rtc.CoolingCapacitySet = false
+	rtc.HeatingDemandSet = false
+	rtc.CoolingDemandSet = false
+	rtc.FanLevelSet = false
+	rtc.FanManualSet = false
+	rtc.FanStageHeatingSet = false
+	rtc.FanStageCoolingSet = false
+	rtc.OnSet = false
+	rtc.EcoSet = false
+	rtc.StateIndicationSet = false
+	rtc.ErrorCodeSet = false
+}
+
+// SetTarget sets the absolute set point temperature in °C (AL_ABSOLUTE_SET_POINT_REQUEST).
+func (rtc *RoomTemperatureControllerUnit) SetTarget(ctx context.Context, degree float64) error {
+	if degree < MinTargetDegree || degree > MaxTargetDegree {
+		return fmt.Errorf("illegal target temperature %.1f°C, has to be %.0f..%.0f: %w", degree, MinTargetDegree, MaxTargetDegree, ErrInvalidValue)
+	}
+	return rtc.putInput(ctx, AL_ABSOLUTE_SET_POINT_REQUEST, degree)
+}
+
+// SetRelativeSetPoint shifts the set point by the given offset in K (AL_RELATIVE_SET_POINT_REQUEST).
+func (rtc *RoomTemperatureControllerUnit) SetRelativeSetPoint(ctx context.Context, offset float64) error {
+	if !(offset >= MinRelativeSetPoint && offset <= MaxRelativeSetPoint) {
+		return fmt.Errorf("illegal set point offset %.1fK, has to be %.0f..%.0f: %w", offset, MinRelativeSetPoint, MaxRelativeSetPoint, ErrInvalidValue)
+	}
+	return rtc.putInput(ctx, AL_RELATIVE_SET_POINT_REQUEST, offset)
+}
+
+// SetEco switches the eco mode on or off (AL_ECO_ON_OFF).
+func (rtc *RoomTemperatureControllerUnit) SetEco(ctx context.Context, eco bool) error {
+	return rtc.putInput(ctx, AL_ECO_ON_OFF, eco)
+}
+
+// SetFanStage sets the stage of a fan coil (AL_FAN_STAGE_REQUEST), 0 switches the fan off.
+func (rtc *RoomTemperatureControllerUnit) SetFanStage(ctx context.Context, stage int) error {
+	if stage < 0 || stage > MaxFanStage {
+		return fmt.Errorf("illegal fan stage %d, has to be 0..%d: %w", stage, MaxFanStage, ErrInvalidValue)
+	}
+	return rtc.putInput(ctx, AL_FAN_STAGE_REQUEST, stage)
+}
+
+// SetFanManual switches between the fan stage set manually and the one chosen by the controller (AL_FAN_MANUAL_ON_OFF).
+func (rtc *RoomTemperatureControllerUnit) SetFanManual(ctx context.Context, manual bool) error {
+	return rtc.putInput(ctx, AL_FAN_MANUAL_ON_OFF, manual)
+}
+
+// SetOn switches the controller on or off (AL_CONTROLLER_ON_OFF_REQUEST). Off means protection mode.
+func (rtc *RoomTemperatureControllerUnit) SetOn(ctx context.Context, on bool) error {
+	return rtc.putInput(ctx, AL_CONTROLLER_ON_OFF_REQUEST, on)
+}
+
+// HasFault reports whether the device signals an error (AL_INFO_ERROR).
+func (rtc *RoomTemperatureControllerUnit) HasFault() bool {
+	return rtc.ErrorCode != 0
+}
+
+func (rtc *RoomTemperatureControllerUnit) snapshot() Unit {
+	snapshot := *rtc
+	snapshot.UnitData = rtc.snapshotData()
+	return &snapshot
 }
 
 func (rtc *RoomTemperatureControllerUnit) String() string {
@@ -108,12 +366,33 @@ func (rtc *RoomTemperatureControllerUnit) String() string {
 	if rtc.Active == 1 {
 		active = "on "
 	}
-	return fmt.Sprintf("%s %2.2f°C, %2.2f°C, %s (%d%%)", rtc.prtUnitHead(), rtc.ActualDegree, rtc.TargetDegree, active, rtc.Capacity)
+	mode := ""
+	if !rtc.On {
+		mode += " OFF"
+	}
+	if rtc.Eco {
+		mode += " eco"
+	}
+	if rtc.CoolingActive {
+		mode += fmt.Sprintf(" cooling (%d%%)", rtc.CoolingCapacity)
+	}
+	if rtc.FanLevel != 0 || rtc.FanManual {
+		mode += fmt.Sprintf(" fan %d", rtc.FanLevel)
+		if rtc.FanManual {
+			mode += " manual"
+		}
+	}
+	fault := ""
+	if rtc.HasFault() {
+		fault = fmt.Sprintf(" FAULT %d", rtc.ErrorCode)
+	}
+	return fmt.Sprintf("%s %2.2f°C, %2.2f°C, %s (%d%%)%s%s", rtc.prtUnitHead(), rtc.ActualDegree, rtc.TargetDegree, active, rtc.Capacity, mode, fault)
 }
 
-func roomTemperatureControllerFactory(deviceId string, device *Device, channelId string) Unit {
+func roomTemperatureControllerFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	rtc := RoomTemperatureControllerUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeRoomTemperatureController),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeRoomTemperatureController),
+		On:       true, // controllers without AL_CONTROLLER_ON_OFF output are always on
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
@@ -0,0 +1,61 @@
+package fahapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRoomTemperatureControllerFanCoil(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testDevice("RTC", FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITH_FAN,
+			map[string]*InOutPut{
+				"idp0000": datapoint(AL_RELATIVE_SET_POINT_REQUEST, "0"),
+				"idp0001": datapoint(AL_FAN_STAGE_REQUEST, "0"),
+				"idp0002": datapoint(AL_FAN_MANUAL_ON_OFF, "0"),
+			},
+			map[string]*InOutPut{
+				"odp0000": datapoint(AL_FAN_COIL_LEVEL, "2"),
+				"odp0001": datapoint(AL_FAN_MANUAL_ON_OFF, "1"),
+				"odp0002": datapoint(AL_ACTUATING_FAN_STAGE_HEATING, "3"),
+				"odp0003": datapoint(AL_ACTUATING_FAN_STAGE_COOLING, "1"),
+			}),
+	})
+	c := startClient(t, f)
+	rtc := CastRTC(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+	if rtc.FanLevel != 2 || !rtc.FanManual || rtc.FanStageHeating != 3 || rtc.FanStageCooling != 1 {
+		t.Errorf("fan level %d, manual %v, stage heating %d, cooling %d", rtc.FanLevel, rtc.FanManual, rtc.FanStageHeating, rtc.FanStageCooling)
+	}
+
+	tests := []struct {
+		name     string
+		set      func(ctx context.Context) error
+		wantBody string // "" if the value has to be rejected
+		wantDp   string
+	}{
+		{"relative set point", func(ctx context.Context) error { return rtc.SetRelativeSetPoint(ctx, -1.5) }, "-1.5", "idp0000"},
+		{"relative set point too high", func(ctx context.Context) error { return rtc.SetRelativeSetPoint(ctx, MaxRelativeSetPoint+0.5) }, "", ""},
+		{"relative set point too low", func(ctx context.Context) error { return rtc.SetRelativeSetPoint(ctx, MinRelativeSetPoint-0.5) }, "", ""},
+		{"fan stage", func(ctx context.Context) error { return rtc.SetFanStage(ctx, 1) }, "1", "idp0001"},
+		{"fan stage too high", func(ctx context.Context) error { return rtc.SetFanStage(ctx, MaxFanStage+1) }, "", ""},
+		{"fan stage negative", func(ctx context.Context) error { return rtc.SetFanStage(ctx, -1) }, "", ""},
+		{"fan manual", func(ctx context.Context) error { return rtc.SetFanManual(ctx, true) }, "1", "idp0002"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := len(f.recordedPuts())
+			err := test.set(testContext(t))
+			puts := f.recordedPuts()[before:]
+			if test.wantBody == "" {
+				if !errors.Is(err, ErrInvalidValue) || len(puts) > 0 {
+					t.Errorf("got error %v and %d PUTs, want ErrInvalidValue", err, len(puts))
+				}
+				return
+			}
+			wantPath := "/api/rest/datapoint/" + testSysAP + "/ABB700000001.ch0000." + test.wantDp
+			if err != nil || len(puts) != 1 || puts[0].Path != wantPath || puts[0].Body != test.wantBody {
+				t.Errorf("got error %v and PUTs %+v, want %s to %s", err, puts, test.wantBody, wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package fahapi
+
+import (
+	"context"
+	json2 "encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Scene is a scene configured on the SysAP. The SysAP exposes scenes as devices with scene function IDs.
+// The local API doesn't tell which channels belong to a scene, so Inputs and Outputs are the datapoints
+// of the scene channel itself, keyed by datapoint id.
+type Scene struct {
+	SysApId    string
+	SceneId    string // device serial
+	ChannelId  string
+	Name       string
+	FunctionID FunctionIdType
+	Floor      string
+	Room       string
+	Inputs     map[string]*InOutPut
+	Outputs    map[string]*InOutPut
+}
+
+func isSceneFunctionId(functionId FunctionIdType) bool {
+	switch functionId {
+	case FID_SCENE, FID_SPECIAL_SCENE_PANIC, FID_SPECIAL_SCENE_ALL_OFF,
+		FID_SPECIAL_SCENE_ALL_BLINDS_UP, FID_SPECIAL_SCENE_ALL_BLINDS_DOWN:
+		return true
+	}
+	return false
+}
+
+// Scenes returns all scenes of all SysAPs, sorted by name.
+func (c *Client) Scenes() []*Scene {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	var scenes []*Scene
+	for sysapId, devices := range c.freeDevices {
+		for deviceId, device := range devices {
+			if scene := c.sceneFromDevice(sysapId, deviceId, device); scene != nil {
+				scenes = append(scenes, scene)
+			}
+		}
+	}
+	sort.Slice(scenes, func(i, j int) bool {
+		return scenes[i].Name < scenes[j].Name || (scenes[i].Name == scenes[j].Name && scenes[i].SceneId < scenes[j].SceneId)
+	})
+	return scenes
+}
+
+// LookupScene returns the scene or nil, if the SysAP has no such scene.
+func (c *Client) LookupScene(sysapId string, sceneId string) *Scene {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	if device, ok := c.freeDevices[sysapId][sceneId]; ok {
+		return c.sceneFromDevice(sysapId, sceneId, device)
+	}
+	return nil
+}
+
+// TriggerScene triggers the scene like a wall panel would do. It writes to the datapoint with pairing id
+// AL_SCENE_CONTROL of the scene (inputs first, then outputs).
+func (c *Client) TriggerScene(ctx context.Context, sysapId string, sceneId string) error {
+	scene := c.LookupScene(sysapId, sceneId)
+	if scene == nil {
+		return fmt.Errorf("unknown scene %s on SysAP %s: %w", sceneId, sysapId, ErrNotFound)
+	}
+
+	datapointId := sceneControlDatapoint(scene)
+	if datapointId == "" {
+		return fmt.Errorf("scene %s on SysAP %s has no datapoint with pairing id AL_SCENE_CONTROL: %w", sceneId, sysapId, ErrNotFound)
+	}
+
+	ok, err := c.PutDatapoint(ctx, sysapId, sceneId, scene.ChannelId, datapointId, "1")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("SysAP didn't accept triggering scene %s", sceneId)
+	}
+	return nil
+}
+
+// sceneControlDatapoint returns the id of the AL_SCENE_CONTROL datapoint of the scene or "", if there is none.
+func sceneControlDatapoint(scene *Scene) string {
+	for _, inOutPuts := range []map[string]*InOutPut{scene.Inputs, scene.Outputs} {
+		ids := make([]string, 0, len(inOutPuts))
+		for id := range inOutPuts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if inOut := inOutPuts[id]; inOut != nil && inOut.PairingID != nil && *inOut.PairingID == AL_SCENE_CONTROL {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// sceneFromDevice returns the scene of the first scene channel of the device or nil, if it is no scene.
+// The caller has to hold the read lock of stateMutex.
+func (c *Client) sceneFromDevice(sysapId string, deviceId string, device *Device) *Scene {
+	channelIds := make([]string, 0, len(device.Channels))
+	for channelId := range device.Channels {
+		channelIds = append(channelIds, channelId)
+	}
+	sort.Strings(channelIds)
+
+	for _, channelId := range channelIds {
+		channel := device.Channels[channelId]
+		if channel == nil || channel.FunctionID == nil || !isSceneFunctionId(FunctionIdType(*channel.FunctionID)) {
+			continue
+		}
+		floor, room := c.getFloorRoom(sysapId, device, channel)
+		name := ""
+		if channel.DisplayName != nil {
+			name = *channel.DisplayName
+		} else if device.DisplayName != nil {
+			name = *device.DisplayName
+		}
+		copied := cloneChannel(channel)
+		return &Scene{
+			SysApId:    sysapId,
+			SceneId:    deviceId,
+			ChannelId:  channelId,
+			Name:       name,
+			FunctionID: FunctionIdType(*channel.FunctionID),
+			Floor:      floor,
+			Room:       room,
+			Inputs:     copied.Inputs,
+			Outputs:    copied.Outputs,
+		}
+	}
+	return nil
+}
+
+func (c *Client) sceneTriggeredEvent(sysapId string, sceneId string, payload json2.RawMessage) *SceneTriggeredEvent {
+	event := &SceneTriggeredEvent{
+		eventHead: newEventHead(),
+		SysApId:   sysapId,
+		SceneId:   sceneId,
+		Scene:     c.LookupScene(sysapId, sceneId),
+		Raw:       payload,
+	}
+
+	var trigger struct {
+		Channels map[string]*Channel `json:"channels"`
+	}
+	if err := json2.Unmarshal(payload, &trigger); err == nil {
+		event.Channels = trigger.Channels
+	}
+	return event
+}
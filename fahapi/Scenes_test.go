@@ -0,0 +1,52 @@
+package fahapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTriggerScene(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   map[string]*InOutPut
+		outputs  map[string]*InOutPut
+		wantPath string // "" if the trigger has to fail with ErrNotFound
+	}{
+		{
+			name:     "scene control input",
+			inputs:   map[string]*InOutPut{"idp0000": datapoint(AL_SWITCH_ON_OFF, "0"), "idp0001": datapoint(AL_SCENE_CONTROL, "0")},
+			wantPath: "/api/rest/datapoint/" + testSysAP + "/FFFF48010001.ch0000.idp0001",
+		},
+		{
+			name:     "scene control output",
+			outputs:  map[string]*InOutPut{"odp0000": datapoint(AL_SCENE_CONTROL, "0")},
+			wantPath: "/api/rest/datapoint/" + testSysAP + "/FFFF48010001.ch0000.odp0000",
+		},
+		{
+			name:   "no scene control",
+			inputs: map[string]*InOutPut{"idp0000": datapoint(AL_SWITCH_ON_OFF, "0")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"FFFF48010001": testDevice("Scene", FID_SCENE, test.inputs, test.outputs)})
+			c := startClient(t, f)
+
+			err := c.TriggerScene(testContext(t), testSysAP, "FFFF48010001")
+			puts := f.recordedPuts()
+			if test.wantPath == "" {
+				if !errors.Is(err, ErrNotFound) || len(puts) > 0 {
+					t.Errorf("got error %v and %d PUTs, want ErrNotFound", err, len(puts))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(puts) != 1 || puts[0].Path != test.wantPath || puts[0].Body != "1" {
+				t.Errorf("PUTs %+v, want 1 to %s", puts, test.wantPath)
+			}
+		})
+	}
+}
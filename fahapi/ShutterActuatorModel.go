@@ -0,0 +1,237 @@
+package fahapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ShutterActuatorUnit covers shutter, roller blind, attic window and awning actuators.
+// Positions are percentages, 0 = open (up), 100 = closed (down).
+type ShutterActuatorUnit struct {
+	UnitData
+	MoveState       int // AL_INFO_MOVE_UP_DOWN, see ShutterNotMoving etc.
+	MoveStateSet    bool
+	Position        int
+	PositionSet     bool
+	SlatPosition    int
+	SlatPositionSet bool
+	ForceState      int // AL_INFO_FORCE, 0 = not forced
+	ForceStateSet   bool
+	WindAlarm       bool
+	WindAlarmSet    bool
+	FrostAlarm      bool
+	FrostAlarmSet   bool
+	RainAlarm       bool
+	RainAlarmSet    bool
+}
+
+const UntTypeShutterActuator UnitTypeConst = "AcShuttr"
+
+// values of AL_INFO_MOVE_UP_DOWN
+const (
+	ShutterNotMoving  = 0
+	ShutterMovingUp   = 2
+	ShutterMovingDown = 3
+)
+
+func CastSHU(u Unit) *ShutterActuatorUnit {
+	if typeSave, ok := u.(*ShutterActuatorUnit); ok {
+		return typeSave
+	}
+	log.Print("CastSHU - wrong type\n")
+	return nil
+}
+
+func (shu *ShutterActuatorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
+	changed := false
+
+	switch *outPut.PairingID {
+	case AL_INFO_FORCE: // Indicates the cause of forced operation (0 = not forced)
+		value, ok := shu.decode(outPut)
+		if !ok {
+			break
+		}
+		force := value.Int
+		if force != shu.ForceState {
+			shu.ForceState = force
+			shu.ForceStateSet = true
+			changed = true
+		}
+	case AL_INFO_MOVE_UP_DOWN: // Indicates the movement of the blind
+		value, ok := shu.decode(outPut)
+		if !ok {
+			break
+		}
+		moveState := value.Int
+		if moveState != shu.MoveState {
+			shu.MoveState = moveState
+			shu.MoveStateSet = true
+			changed = true
+		}
+	case AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE:
+		value, ok := shu.decode(outPut)
+		if !ok {
+			break
+		}
+		position := value.Int
+		if position != shu.Position {
+			shu.Position = position
+			shu.PositionSet = true
+			changed = true
+		}
+	case AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE:
+		value, ok := shu.decode(outPut)
+		if !ok {
+			break
+		}
+		slatPosition := value.Int
+		if slatPosition != shu.SlatPosition {
+			shu.SlatPosition = slatPosition
+			shu.SlatPositionSet = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// the alarms are inputs of the actuator, sent by a weather station
+func (shu *ShutterActuatorUnit) updateUnitFromInDatapoint(inPut *InOutPut) bool {
+	if inPut.PairingID == nil {
+		return false
+	}
+	switch *inPut.PairingID {
+	case AL_WIND_ALARM, AL_FROST_ALARM, AL_RAIN_ALARM:
+	default:
+		return false
+	}
+	value, ok := shu.decode(inPut)
+	if !ok {
+		return false
+	}
+	changed := false
+	alarm := value.Bool
+
+	switch *inPut.PairingID {
+	case AL_WIND_ALARM:
+		if alarm != shu.WindAlarm {
+			shu.WindAlarm = alarm
+			shu.WindAlarmSet = true
+			changed = true
+		}
+	case AL_FROST_ALARM:
+		if alarm != shu.FrostAlarm {
+			shu.FrostAlarm = alarm
+			shu.FrostAlarmSet = true
+			changed = true
+		}
+	case AL_RAIN_ALARM:
+		if alarm != shu.RainAlarm {
+			shu.RainAlarm = alarm
+			shu.RainAlarmSet = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (shu *ShutterActuatorUnit) resetChanged() {
+	shu.MoveStateSet = false
+	shu.PositionSet = false
+	shu.SlatPositionSet = false
+	shu.ForceStateSet = false
+	shu.WindAlarmSet = false
+	shu.FrostAlarmSet = false
+	shu.RainAlarmSet = false
+}
+
+func (shu *ShutterActuatorUnit) snapshot() Unit {
+	snapshot := *shu
+	snapshot.UnitData = shu.snapshotData()
+	return &snapshot
+}
+
+// MoveUp opens the shutter completely (AL_MOVE_UP_DOWN).
+func (shu *ShutterActuatorUnit) MoveUp(ctx context.Context) error {
+	return shu.putInput(ctx, AL_MOVE_UP_DOWN, 0)
+}
+
+// MoveDown closes the shutter completely (AL_MOVE_UP_DOWN).
+func (shu *ShutterActuatorUnit) MoveDown(ctx context.Context) error {
+	return shu.putInput(ctx, AL_MOVE_UP_DOWN, 1)
+}
+
+// Stop stops a moving shutter (AL_STOP_STEP_UP_DOWN). The same input steps the slats of a blind which isn't
+// moving, so Stop doesn't write anything, if the current state of the shutter has no movement (MoveState).
+// Use SetSlatPosition to move the slats.
+func (shu *ShutterActuatorUnit) Stop(ctx context.Context) error {
+	if shu.client != nil {
+		if current, ok := shu.client.LookupUnit(shu.getUnitMapKey()).(*ShutterActuatorUnit); ok && current.MoveState == ShutterNotMoving {
+			return nil
+		}
+	}
+	return shu.putInput(ctx, AL_STOP_STEP_UP_DOWN, 1)
+}
+
+// SetPosition moves the shutter to the given position in percent (AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE).
+func (shu *ShutterActuatorUnit) SetPosition(ctx context.Context, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("illegal shutter position %d, has to be 0..100: %w", percent, ErrInvalidValue)
+	}
+	return shu.putInput(ctx, AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, percent)
+}
+
+// SetSlatPosition moves the slats to the given position in percent (AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE).
+func (shu *ShutterActuatorUnit) SetSlatPosition(ctx context.Context, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("illegal slat position %d, has to be 0..100: %w", percent, ErrInvalidValue)
+	}
+	return shu.putInput(ctx, AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE, percent)
+}
+
+func (shu *ShutterActuatorUnit) String() string {
+	move := ""
+	switch shu.MoveState {
+	case ShutterMovingUp:
+		move = " moving up"
+	case ShutterMovingDown:
+		move = " moving down"
+	}
+	var alarms []string
+	if shu.WindAlarm {
+		alarms = append(alarms, "wind")
+	}
+	if shu.FrostAlarm {
+		alarms = append(alarms, "frost")
+	}
+	if shu.RainAlarm {
+		alarms = append(alarms, "rain")
+	}
+	extra := ""
+	if shu.ForceState != 0 {
+		extra += fmt.Sprintf(" (forced %d)", shu.ForceState)
+	}
+	if len(alarms) > 0 {
+		extra += " ALARM " + strings.Join(alarms, ",")
+	}
+	name := strings.TrimSpace(*shu.GetChannel().DisplayName)
+	return fmt.Sprintf("%s %s: %3d%% slats %3d%%%s%s", shu.prtUnitHead(), name, shu.Position, shu.SlatPosition, move, extra)
+}
+
+func shutterActuatorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
+	shu := ShutterActuatorUnit{
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeShutterActuator),
+	}
+
+	for _, inOut := range device.Channels[channelId].Outputs {
+		shu.updateUnitFromOutDatapoint(inOut)
+	}
+	for _, inOut := range device.Channels[channelId].Inputs {
+		shu.updateUnitFromInDatapoint(inOut)
+	}
+
+	return &shu
+}
@@ -0,0 +1,140 @@
+package fahapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func testShutter(functionId FunctionIdType, moveState, position, slats string) *Device {
+	return testDevice("Blind", functionId,
+		map[string]*InOutPut{
+			"idp0000": datapoint(AL_MOVE_UP_DOWN, "0"),
+			"idp0001": datapoint(AL_STOP_STEP_UP_DOWN, "0"),
+			"idp0002": datapoint(AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, "0"),
+			"idp0003": datapoint(AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE, "0"),
+			"idp0004": datapoint(AL_WIND_ALARM, "0"),
+			"idp0005": datapoint(AL_FROST_ALARM, "1"),
+			"idp0006": datapoint(AL_RAIN_ALARM, "0"),
+		},
+		map[string]*InOutPut{
+			"odp0000": datapoint(AL_INFO_MOVE_UP_DOWN, moveState),
+			"odp0001": datapoint(AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, position),
+			"odp0002": datapoint(AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE, slats),
+			"odp0003": datapoint(AL_INFO_FORCE, "0"),
+		})
+}
+
+func TestShutterActuatorHydration(t *testing.T) {
+	functionIds := []FunctionIdType{FID_SHUTTER_ACTUATOR, FID_ROLLER_BLIND_ACTUATOR, FID_ATTIC_WINDOW_ACTUATOR, FID_AWNING_ACTUATOR}
+	for _, functionId := range functionIds {
+		info, _ := LookupFunctionId(functionId)
+		t.Run(info.Name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testShutter(functionId, "3", "40", "75")})
+			c := startClient(t, f)
+
+			shu, ok := c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000").(*ShutterActuatorUnit)
+			if !ok {
+				t.Fatalf("got %T, want *ShutterActuatorUnit", c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+			}
+			if shu.Type != UntTypeShutterActuator {
+				t.Errorf("unit type %s, want %s", shu.Type, UntTypeShutterActuator)
+			}
+			if shu.MoveState != ShutterMovingDown || shu.Position != 40 || shu.SlatPosition != 75 || shu.ForceState != 0 {
+				t.Errorf("move state %d, position %d, slats %d, force %d", shu.MoveState, shu.Position, shu.SlatPosition, shu.ForceState)
+			}
+			if shu.WindAlarm || !shu.FrostAlarm || shu.RainAlarm {
+				t.Errorf("alarms wind %v, frost %v, rain %v, want frost only", shu.WindAlarm, shu.FrostAlarm, shu.RainAlarm)
+			}
+		})
+	}
+}
+
+func TestShutterActuatorUpdates(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testShutter(FID_SHUTTER_ACTUATOR, "0", "0", "0")})
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	sendDatapoints(t, conn, map[string]string{
+		"ABB700000001/ch0000/odp0000": "2",
+		"ABB700000001/ch0000/odp0001": "100",
+		"ABB700000001/ch0000/odp0002": "50",
+		"ABB700000001/ch0000/odp0003": "3",
+		"ABB700000001/ch0000/idp0004": "1",
+		"ABB700000001/ch0000/idp0005": "0",
+		"ABB700000001/ch0000/idp0006": "1",
+	})
+	waitFor(t, "shutter update", func() bool { return CastSHU(c.LookupUnit(key)).RainAlarm })
+	shu := CastSHU(c.LookupUnit(key))
+	if shu.MoveState != ShutterMovingUp || shu.Position != 100 || shu.SlatPosition != 50 || shu.ForceState != 3 {
+		t.Errorf("move state %d, position %d, slats %d, force %d", shu.MoveState, shu.Position, shu.SlatPosition, shu.ForceState)
+	}
+	if !shu.WindAlarm || shu.FrostAlarm || !shu.RainAlarm {
+		t.Errorf("alarms wind %v, frost %v, rain %v, want wind and rain", shu.WindAlarm, shu.FrostAlarm, shu.RainAlarm)
+	}
+
+	// out of range positions are reported and skipped
+	sub := c.Subscribe(10, EventTypeFilter(EventError))
+	defer sub.Close()
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0001": "101"})
+	if event := nextEvent(t, sub).(*ErrorEvent); !errors.Is(event.Err, ErrInvalidValue) {
+		t.Errorf("got error %v, want ErrInvalidValue", event.Err)
+	}
+	if shu = CastSHU(c.LookupUnit(key)); shu.Position != 100 {
+		t.Errorf("position %d, want 100", shu.Position)
+	}
+}
+
+func TestShutterActuatorWrites(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testShutter(FID_SHUTTER_ACTUATOR, "0", "0", "0")})
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+	shu := CastSHU(c.LookupUnit(key))
+
+	tests := []struct {
+		name     string
+		set      func(ctx context.Context) error
+		wantBody string // "" if nothing is written
+		wantDp   string
+		wantErr  error
+	}{
+		{"move up", shu.MoveUp, "0", "idp0000", nil},
+		{"move down", shu.MoveDown, "1", "idp0000", nil},
+		{"stop while not moving", shu.Stop, "", "", nil},
+		{"position", func(ctx context.Context) error { return shu.SetPosition(ctx, 40) }, "40", "idp0002", nil},
+		{"position too high", func(ctx context.Context) error { return shu.SetPosition(ctx, 101) }, "", "", ErrInvalidValue},
+		{"position negative", func(ctx context.Context) error { return shu.SetPosition(ctx, -1) }, "", "", ErrInvalidValue},
+		{"slat position", func(ctx context.Context) error { return shu.SetSlatPosition(ctx, 30) }, "30", "idp0003", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := len(f.recordedPuts())
+			err := test.set(testContext(t))
+			puts := f.recordedPuts()[before:]
+			if test.wantBody == "" {
+				if !errors.Is(err, test.wantErr) || len(puts) > 0 {
+					t.Errorf("got error %v and PUTs %+v, want error %v and no PUT", err, puts, test.wantErr)
+				}
+				return
+			}
+			wantPath := "/api/rest/datapoint/" + testSysAP + "/ABB700000001.ch0000." + test.wantDp
+			if err != nil || len(puts) != 1 || puts[0].Path != wantPath || puts[0].Body != test.wantBody {
+				t.Errorf("got error %v and PUTs %+v, want %s to %s", err, puts, test.wantBody, wantPath)
+			}
+		})
+	}
+
+	// Stop uses the current state, not the one of the unit it is called on
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "3"})
+	waitFor(t, "moving down", func() bool { return CastSHU(c.LookupUnit(key)).MoveState == ShutterMovingDown })
+	before := len(f.recordedPuts())
+	if err := shu.Stop(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	wantPath := "/api/rest/datapoint/" + testSysAP + "/ABB700000001.ch0000.idp0001"
+	if puts := f.recordedPuts()[before:]; len(puts) != 1 || puts[0].Path != wantPath || puts[0].Body != "1" {
+		t.Errorf("stop of a moving shutter: PUTs %+v, want 1 to %s", puts, wantPath)
+	}
+}
@@ -0,0 +1,167 @@
+package fahapi
+
+import "sort"
+
+// Accessors for the device and unit state of the client. They are safe to be called concurrently
+// with the websocket loop and return copies, which are not changed by later updates.
+
+// LookupUnit returns a snapshot of the unit with the given key (<SysAP UUID>/<device serial>.<channel>)
+// or nil, if there is no such unit.
+func (c *Client) LookupUnit(key string) Unit {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	if unit, ok := c.unitMap[key]; ok {
+		return unit.snapshot()
+	}
+	return nil
+}
+
+// LookupChannelUnit returns a snapshot of the unit of the given channel or nil, if there is no such unit.
+func (c *Client) LookupChannelUnit(sysapId, deviceId, channelId string) Unit {
+	return c.LookupUnit(getUnitMapKey(sysapId, deviceId, channelId))
+}
+
+// AllUnits returns snapshots of all units, sorted by floor and room.
+func (c *Client) AllUnits() []Unit {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	keys := c.getUnitMapKeysSortedByFloorRoom()
+	units := make([]Unit, len(keys))
+	for i, key := range keys {
+		units[i] = c.unitMap[key].snapshot()
+	}
+	return units
+}
+
+// LookupDevice returns a copy of the device or nil, if the SysAP has no such device.
+func (c *Client) LookupDevice(sysapId, deviceId string) *Device {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	if device, ok := c.freeDevices[sysapId][deviceId]; ok {
+		return cloneDevice(device)
+	}
+	return nil
+}
+
+// DeviceIds returns the serials of all devices of the SysAP.
+func (c *Client) DeviceIds(sysapId string) []string {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	ids := make([]string, 0, len(c.freeDevices[sysapId]))
+	for id := range c.freeDevices[sysapId] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SysAPIds returns the UUIDs of all SysAPs known to the client.
+func (c *Client) SysAPIds() []string {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	ids := make([]string, 0, len(c.sysAPConfiguration))
+	for id := range c.sysAPConfiguration {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LookupSysAP returns a copy of the configuration of the SysAP or nil, if it is unknown.
+func (c *Client) LookupSysAP(sysapId string) *SysAP {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	sysap, ok := c.sysAPConfiguration[sysapId]
+	if !ok {
+		return nil
+	}
+	copied := *sysap
+	copied.Devices = make(map[string]*Device, len(c.freeDevices[sysapId]))
+	for id, device := range c.freeDevices[sysapId] {
+		copied.Devices[id] = cloneDevice(device)
+	}
+	return &copied
+}
+
+// GetUnitMapKey returns the key of the unit, as used by LookupUnit.
+func (u *UnitData) GetUnitMapKey() string {
+	return u.getUnitMapKey()
+}
+
+func cloneDevice(device *Device) *Device {
+	if device == nil {
+		return nil
+	}
+	copied := *device
+	if device.Channels != nil {
+		copied.Channels = make(map[string]*Channel, len(device.Channels))
+		for id, channel := range device.Channels {
+			copied.Channels[id] = cloneChannel(channel)
+		}
+	}
+	return &copied
+}
+
+func cloneChannel(channel *Channel) *Channel {
+	if channel == nil {
+		return nil
+	}
+	copied := *channel
+	copied.Inputs = cloneInOutPuts(channel.Inputs)
+	copied.Outputs = cloneInOutPuts(channel.Outputs)
+	return &copied
+}
+
+func cloneInOutPuts(inOutPuts map[string]*InOutPut) map[string]*InOutPut {
+	if inOutPuts == nil {
+		return nil
+	}
+	copied := make(map[string]*InOutPut, len(inOutPuts))
+	for id, inOut := range inOutPuts {
+		if inOut == nil {
+			copied[id] = nil
+			continue
+		}
+		inOutCopy := *inOut
+		copied[id] = &inOutCopy
+	}
+	return copied
+}
+
+// compactDevices drops devices, channels and datapoints sent as null by the SysAP, so that the
+// rest of the package doesn't have to check every entry.
+func compactDevices(devices map[string]*Device) {
+	for id, device := range devices {
+		if device == nil {
+			delete(devices, id)
+			continue
+		}
+		compactDevice(device)
+	}
+}
+
+func compactDevice(device *Device) *Device {
+	for id, channel := range device.Channels {
+		if channel == nil {
+			delete(device.Channels, id)
+			continue
+		}
+		compactInOutPuts(channel.Inputs)
+		compactInOutPuts(channel.Outputs)
+	}
+	return device
+}
+
+func compactInOutPuts(inOutPuts map[string]*InOutPut) {
+	for id, inOut := range inOutPuts {
+		if inOut == nil {
+			delete(inOutPuts, id)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package fahapi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentStateAccess(t *testing.T) {
+	devices := map[string]*Device{
+		"ABB700000001": testSwitch("Light 1", "0"),
+		"ABB700000002": testSwitch("Light 2", "0"),
+	}
+	f := newFakeSysAP(t, devices)
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if unit := c.LookupUnit(key); unit != nil {
+					_ = CastSAU(unit).On
+					_ = unit.GetChannel()
+					_ = unit.String()
+				}
+				for _, unit := range c.AllUnits() {
+					_ = unit.GetUnitData().LastUpdate
+				}
+				if device := c.LookupDevice(testSysAP, "ABB700000002"); device != nil {
+					_ = *device.Channels["ch0000"].Outputs["odp0000"].Value
+				}
+				_ = c.LookupSysAP(testSysAP)
+				_ = c.DeviceIds(testSysAP)
+			}
+		}()
+	}
+
+	const updates = 100
+	for i := 1; i <= updates; i++ {
+		sendDatapoints(t, conn, map[string]string{
+			"ABB700000001/ch0000/odp0000": fmt.Sprint(i % 2),
+			"ABB700000002/ch0000/odp0000": fmt.Sprint(i % 2),
+		})
+	}
+	sendDatapoints(t, conn, map[string]string{"ABB700000002/ch0000/odp0000": "1"})
+	waitFor(t, "last update", func() bool {
+		return *c.LookupDevice(testSysAP, "ABB700000002").Channels["ch0000"].Outputs["odp0000"].Value == "1" &&
+			!CastSAU(c.LookupUnit(key)).On
+	})
+	close(stop)
+	readers.Wait()
+}
+
+func TestSnapshotsDontChange(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+	before := CastSAU(c.LookupUnit(key))
+	device := c.LookupDevice(testSysAP, "ABB700000001")
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "1"})
+	waitFor(t, "switched on", func() bool { return CastSAU(c.LookupUnit(key)).On })
+
+	if before.On {
+		t.Error("snapshot taken before the update changed")
+	}
+	if value := *device.Channels["ch0000"].Outputs["odp0000"].Value; value != "0" {
+		t.Errorf("copy of the device changed to %s", value)
+	}
+}
@@ -1,6 +1,7 @@
 package fahapi
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -28,15 +29,23 @@ func (sau *SwitchActuatorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool
 	changed := false
 
 	switch *outPut.PairingID {
-	case 0x0100: // AL_INFO_ON_OFF (Reflects the binary state of the actuator)
-		on := *outPut.Value != "0"
+	case AL_INFO_ON_OFF: // Reflects the binary state of the actuator
+		value, ok := sau.decode(outPut)
+		if !ok {
+			break
+		}
+		on := value.Bool
 		if on != sau.On {
 			sau.On = on
 			sau.OnSet = true
 			changed = true
 		}
-	case 0x0101: // AL_INFO_FORCE (Indicates the cause of forced operation (0 = not forced))
-		force := *outPut.Value != "0"
+	case AL_INFO_FORCE: // Indicates the cause of forced operation (0 = not forced)
+		value, ok := sau.decode(outPut)
+		if !ok {
+			break
+		}
+		force := value.Bool
 		if force != sau.Force {
 			sau.Force = force
 			sau.ForceSet = true
@@ -52,6 +61,17 @@ func (sau *SwitchActuatorUnit) resetChanged() {
 	sau.ForceSet = false
 }
 
+func (sau *SwitchActuatorUnit) snapshot() Unit {
+	snapshot := *sau
+	snapshot.UnitData = sau.snapshotData()
+	return &snapshot
+}
+
+// SetOn switches the actuator on or off (AL_SWITCH_ON_OFF).
+func (sau *SwitchActuatorUnit) SetOn(ctx context.Context, on bool) error {
+	return sau.putInput(ctx, AL_SWITCH_ON_OFF, on)
+}
+
 func (sau *SwitchActuatorUnit) String() string {
 	on := "OFF"
 	if sau.On {
@@ -65,9 +85,9 @@ func (sau *SwitchActuatorUnit) String() string {
 	return fmt.Sprintf("%s %s: %s%s", sau.prtUnitHead(), name, on, force)
 }
 
-func switchActuatorFactory(deviceId string, device *Device, channelId string) Unit {
+func switchActuatorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	sau := SwitchActuatorUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeSwitchActuator),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeSwitchActuator),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
@@ -0,0 +1,42 @@
+package fahapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSwitchActuatorSetOn(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testSwitch("Light", "0"),
+		"ABB700000002": testDevice("Status", FID_SWITCH_ACTUATOR, nil,
+			map[string]*InOutPut{"odp0000": datapoint(AL_INFO_ON_OFF, "0")}),
+	})
+	c := startClient(t, f)
+	sau := CastSAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+
+	wantPath := "/api/rest/datapoint/" + testSysAP + "/ABB700000001.ch0000.idp0000"
+	for _, test := range []struct {
+		on   bool
+		want string
+	}{{true, "1"}, {false, "0"}} {
+		before := len(f.recordedPuts())
+		err := sau.SetOn(testContext(t), test.on)
+		if puts := f.recordedPuts()[before:]; err != nil || len(puts) != 1 || puts[0].Path != wantPath || puts[0].Body != test.want {
+			t.Errorf("SetOn(%v): got error %v and PUTs %+v, want %s to %s", test.on, err, puts, test.want, wantPath)
+		}
+	}
+
+	// the SysAP doesn't accept the value
+	f.setPutResult("failed", 0)
+	if err := sau.SetOn(testContext(t), true); err == nil {
+		t.Error("SetOn succeeded, although the SysAP didn't accept the value")
+	}
+	f.setPutResult("OK", 0)
+
+	// a channel without AL_SWITCH_ON_OFF input can't be switched
+	before := len(f.recordedPuts())
+	status := CastSAU(c.LookupChannelUnit(testSysAP, "ABB700000002", "ch0000"))
+	if err := status.SetOn(testContext(t), true); !errors.Is(err, ErrNotFound) || len(f.recordedPuts()) != before {
+		t.Errorf("got error %v, want ErrNotFound and no PUT", err)
+	}
+}
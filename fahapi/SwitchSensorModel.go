@@ -25,35 +25,39 @@ func (ssu *SwitchSensorUnit) updateUnitFromOutDatapoint(outPut *InOutPut) bool {
 	changed := false
 
 	switch *outPut.PairingID {
-	case 0x0001: // AL_SWITCH_ON_OFF (Binary Switch value)
-		on := *outPut.Value != "0"
+	case AL_SWITCH_ON_OFF: // Binary Switch value
+		value, ok := ssu.decode(outPut)
+		if !ok {
+			break
+		}
+		on := value.Bool
 		if on != ssu.On {
 			ssu.On = on
 			ssu.OnSet = true
 			changed = true
 		}
-	case 0x0002: // AL_TIMED_START_STOP (For staircase lighning or movement detection)
-	case 0x0003: // AL_FORCED
-	case 0x0004: // AL_SCENE_CONTROL
-	case 0x0010: // AL_RELATIVE_SET_VALUE_CONTROL
-	case 0x0020: // AL_MOVE_UP_DOWN
-	case 0x0021: // AL_STOP_STEP_UP_DOWN
-	case 0x0028: // AL_FORCED_UP_DOWN
-	case 0x0440: // AL_MEDIA_PLAY
-	case 0x0441: // AL_MEDIA_PAUSE
-	case 0x0442: // AL_MEDIA_NEXT
-	case 0x0443: // AL_MEDIA_PREVIOUS
-	case 0x0444: // AL_MEDIA_PLAY_MODE
-	case 0x0445: // AL_MEDIA_MUTE
-	case 0x0446: // AL_RELATIVE_VOLUME_CONTROL
-	case 0x0447: // AL_ABSOLUTE_VOLUME_CONTROL
-	case 0x0448: // AL_GROUP_MEMBERSHIP
-	case 0x0449: // AL_PLAY_FAVORITE
-	case 0x044a: // AL_PLAY_NEXT_FAVORITE
-	case 0x0460: // AL_PLAYBACK_STATUS
-	case 0x0160: // AL_RELATIVE_FAN_SPEED_CONTROL
-	case 0x0161: // AL_ABSOLUTE_FAN_SPEED_CONTROL
-	case 0xf101: // AL_SWITCH_ENTITY_ON_OFF (Switch entity On/Off; Entity control e.g. activate an alert or timer program)
+	case AL_TIMED_START_STOP: // For staircase lighning or movement detection
+	case AL_FORCED:
+	case AL_SCENE_CONTROL:
+	case AL_RELATIVE_SET_VALUE_CONTROL:
+	case AL_MOVE_UP_DOWN:
+	case AL_STOP_STEP_UP_DOWN:
+	case AL_FORCED_UP_DOWN:
+	case AL_MEDIA_PLAY:
+	case AL_MEDIA_PAUSE:
+	case AL_MEDIA_NEXT:
+	case AL_MEDIA_PREVIOUS:
+	case AL_MEDIA_PLAY_MODE:
+	case AL_MEDIA_MUTE:
+	case AL_RELATIVE_VOLUME_CONTROL:
+	case AL_ABSOLUTE_VOLUME_CONTROL:
+	case AL_GROUP_MEMBERSHIP:
+	case AL_PLAY_FAVORITE:
+	case AL_PLAY_NEXT_FAVORITE:
+	case AL_PLAYBACK_STATUS:
+	case AL_RELATIVE_FAN_SPEED_CONTROL:
+	case AL_ABSOLUTE_FAN_SPEED_CONTROL:
+	case AL_SWITCH_ENTITY_ON_OFF: // Switch entity On/Off; Entity control e.g. activate an alert or timer program
 	}
 
 	return changed
@@ -63,6 +67,12 @@ func (ssu *SwitchSensorUnit) resetChanged() {
 	ssu.OnSet = false
 }
 
+func (ssu *SwitchSensorUnit) snapshot() Unit {
+	snapshot := *ssu
+	snapshot.UnitData = ssu.snapshotData()
+	return &snapshot
+}
+
 func (ssu *SwitchSensorUnit) String() string {
 	on := "OFF"
 	if ssu.On {
@@ -71,9 +81,9 @@ func (ssu *SwitchSensorUnit) String() string {
 	return fmt.Sprintf("%s %s: %s ", ssu.prtUnitHead(), *ssu.GetChannel().DisplayName, on)
 }
 
-func switchSensorFactory(deviceId string, device *Device, channelId string) Unit {
+func switchSensorFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	wds := SwitchSensorUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeSwitchSensor),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeSwitchSensor),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
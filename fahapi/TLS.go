@@ -0,0 +1,106 @@
+package fahapi
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TLSOptions configures the HTTPS / wss connection to a SysAP, which usually serves a self-signed certificate.
+type TLSOptions struct {
+	// CACertPEM are the PEM encoded certificates accepted as root CAs (e.g. the SysAP certificate itself).
+	// If empty, the system roots are used.
+	CACertPEM []byte
+	// PinnedSHA256 are the hex encoded SHA-256 fingerprints of accepted server certificates
+	// (colons are allowed). If set without CACertPEM, the pin replaces the verification of the chain.
+	PinnedSHA256 []string
+	// ServerName overrides the name the certificate is verified against (the host by default).
+	ServerName string
+	// InsecureSkipVerify accepts any certificate. Only use it for testing.
+	InsecureSkipVerify bool
+}
+
+// UseTLS switches the client to https for all REST calls and wss for the websocket, using the given options
+// for both. The Transport of the http.Client (http.DefaultTransport, if nil) is cloned with the new TLS config,
+// so call SetHTTPClient before, not after it. Other Transports than *http.Transport can't be configured and
+// are rejected.
+func (c *Client) UseTLS(options TLSOptions) error {
+	config, err := options.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	roundTripper := c.httpClient.Transport
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	transport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("can't configure TLS for transport %T of the http client", roundTripper)
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = config
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+
+	dialer := *c.wsDialer
+	dialer.TLSClientConfig = config
+	c.wsDialer = &dialer
+
+	c.scheme = "https"
+	return nil
+}
+
+func (o TLSOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if len(o.CACertPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(o.CACertPEM) {
+			return nil, fmt.Errorf("no valid certificate found in CACertPEM")
+		}
+		config.RootCAs = pool
+	}
+
+	if len(o.PinnedSHA256) > 0 {
+		pins := make(map[string]bool, len(o.PinnedSHA256))
+		for _, pin := range o.PinnedSHA256 {
+			pin = strings.ToLower(strings.Replace(pin, ":", "", -1))
+			if _, err := hex.DecodeString(pin); err != nil || len(pin) != sha256.Size*2 {
+				return nil, fmt.Errorf("illegal SHA-256 certificate fingerprint %s", pin)
+			}
+			pins[pin] = true
+		}
+		if len(o.CACertPEM) == 0 {
+			config.InsecureSkipVerify = true // the pin check below replaces the chain verification
+		}
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("SysAP sent no certificate")
+			}
+			fingerprint := sha256.Sum256(rawCerts[0])
+			if !pins[hex.EncodeToString(fingerprint[:])] {
+				return fmt.Errorf("SysAP certificate fingerprint %x is not pinned", fingerprint)
+			}
+			return nil
+		}
+	}
+
+	return config, nil
+}
+
+// webSocketScheme returns the websocket scheme matching the REST scheme.
+func (c *Client) webSocketScheme() string {
+	if c.scheme == "https" {
+		return "wss"
+	}
+	return "ws"
+}
@@ -0,0 +1,132 @@
+package fahapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fingerprint returns the pin of the certificate of the fake, in the colon separated form shown by browsers.
+func fingerprint(f *fakeSysAP) string {
+	sum := sha256.Sum256(f.server.Certificate().Raw)
+	var parts []string
+	for _, b := range sum {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(parts, ":")
+}
+
+func TestUseTLS(t *testing.T) {
+	devices := map[string]*Device{"ABB700000001": testSwitch("Light", "1")}
+	certPEM := func(f *fakeSysAP) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.server.Certificate().Raw})
+	}
+	tests := []struct {
+		name    string
+		options func(f *fakeSysAP) TLSOptions
+		wantErr bool
+	}{
+		{
+			name:    "pin match",
+			options: func(f *fakeSysAP) TLSOptions { return TLSOptions{PinnedSHA256: []string{fingerprint(f)}} },
+		},
+		{
+			name: "pin mismatch",
+			options: func(f *fakeSysAP) TLSOptions {
+				return TLSOptions{PinnedSHA256: []string{strings.Repeat("00", sha256.Size)}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "CA verified",
+			options: func(f *fakeSysAP) TLSOptions { return TLSOptions{CACertPEM: certPEM(f)} },
+		},
+		{
+			name:    "unknown CA",
+			options: func(f *fakeSysAP) TLSOptions { return TLSOptions{} },
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeTLSSysAP(t, devices)
+			c := f.client()
+			if err := c.UseTLS(test.options(f)); err != nil {
+				t.Fatalf("UseTLS: %s", err)
+			}
+
+			// REST and websocket share the TLS config
+			transport := c.httpClient.Transport.(*http.Transport)
+			if transport.TLSClientConfig != c.wsDialer.TLSClientConfig {
+				t.Error("websocket dialer doesn't use the TLS config of the http transport")
+			}
+
+			err := c.ReadAndHydradteAllDevices(testContext(t))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ReadAndHydradteAllDevices: %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			conn := startLoop(t, f, c)
+			sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "0"})
+			waitFor(t, "update via wss", func() bool {
+				return !CastSAU(c.LookupUnit(getUnitMapKey(testSysAP, "ABB700000001", "ch0000"))).On
+			})
+		})
+	}
+}
+
+func TestUseTLSWebsocketPinMismatch(t *testing.T) {
+	f := newFakeTLSSysAP(t, nil)
+	c := f.client()
+	if err := c.UseTLS(TLSOptions{PinnedSHA256: []string{strings.Repeat("00", sha256.Size)}}); err != nil {
+		t.Fatalf("UseTLS: %s", err)
+	}
+
+	conn, _, err := c.wsDialer.DialContext(testContext(t), "wss"+strings.TrimPrefix(f.server.URL, "https"), nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("wss connection with a wrong pin succeeded")
+	}
+	if !strings.Contains(err.Error(), "is not pinned") {
+		t.Errorf("got %s, want an error about the pin", err)
+	}
+}
+
+type customTransport struct{}
+
+func (customTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestUseTLSTransport(t *testing.T) {
+	f := newFakeTLSSysAP(t, nil)
+	options := TLSOptions{PinnedSHA256: []string{fingerprint(f)}}
+
+	// settings of the configured transport are kept
+	c := f.client()
+	c.SetHTTPClient(&http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 7}})
+	if err := c.UseTLS(options); err != nil {
+		t.Fatalf("UseTLS: %s", err)
+	}
+	if transport := c.httpClient.Transport.(*http.Transport); transport.MaxIdleConnsPerHost != 7 || transport.TLSClientConfig == nil {
+		t.Errorf("transport %+v, want a clone with TLS config", transport)
+	}
+
+	// other transports can't be configured
+	c = f.client()
+	custom := customTransport{}
+	c.SetHTTPClient(&http.Client{Transport: custom})
+	if err := c.UseTLS(options); err == nil {
+		t.Error("UseTLS replaced a custom transport")
+	}
+	if c.httpClient.Transport != custom {
+		t.Errorf("transport %T, want the custom transport", c.httpClient.Transport)
+	}
+}
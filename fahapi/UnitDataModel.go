@@ -1,18 +1,17 @@
 package fahapi
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"sort"
 	"time"
 )
 
-var UnitMap map[string]Unit
-
 type UnitTypeConst string
 
 // hydrated data structures
 type UnitData struct {
+	SysApId      string
 	SerialNumber string
 	NativeId     *string
 	ChannelId    string
@@ -21,6 +20,9 @@ type UnitData struct {
 	Floor        string
 	Room         string
 	LastUpdate   time.Time
+
+	decodeErrors []error // values which couldn't be decoded, see queueUnitErrors
+	client       *Client // used by units with methods writing to the SysAP
 }
 
 type Unit interface {
@@ -30,6 +32,19 @@ type Unit interface {
 	getUnitMapKey() string
 	updateUnitFromOutDatapoint(outPut *InOutPut) bool
 	resetChanged()
+	snapshot() Unit
+}
+
+// inputUnit is implemented by units which also follow the values of some of their input datapoints
+// (e.g. the alarms sent by a weather station to a shutter actuator).
+type inputUnit interface {
+	updateUnitFromInDatapoint(inPut *InOutPut) bool
+}
+
+// transitionUnit is implemented by units which note the time of transitions reported via websocket.
+// Values read while hydrating or resyncing don't count, as the time they changed is unknown.
+type transitionUnit interface {
+	updateUnitFromTransition(outPut *InOutPut)
 }
 
 func (u *UnitData) GetChannel() *Channel {
@@ -43,6 +58,13 @@ func (u *UnitData) GetUnitData() *UnitData {
 	return u
 }
 
+// snapshotData returns a copy of the unit data with its own copy of the device.
+func (u *UnitData) snapshotData() UnitData {
+	data := *u
+	data.Device = cloneDevice(u.Device)
+	return data
+}
+
 func (u *UnitData) prtUnitHead() string {
 	var updTimeFormat = "15:04:05"
 	//return fmt.Sprintf("%3s %s@%s: %-40s", u.Type, u.getUnitMapKey(), u.LastUpdate.Format(updTimeFormat), name)
@@ -50,31 +72,35 @@ func (u *UnitData) prtUnitHead() string {
 	if u.NativeId != nil {
 		nativeId = *u.NativeId // show only 8 chars of the native Id
 	}
-	return fmt.Sprintf("%s %-8s %s: %-11s / %-16s [%-8s] ", u.getUnitMapKey(), nativeId, u.LastUpdate.Format(updTimeFormat), u.Floor, u.Room, u.Type)
+	return fmt.Sprintf("%s.%s %-8s %s: %-11s / %-16s [%-8s] ", u.SerialNumber, u.ChannelId, nativeId, u.LastUpdate.Format(updTimeFormat), u.Floor, u.Room, u.Type)
 }
 
-func getUnitMapKey(deviceId, channelId string) string {
-	return fmt.Sprintf("%s.%s", deviceId, channelId)
+// unit keys have the format <SysAP UUID>/<device serial>.<channel>
+func getUnitMapKey(sysapId, deviceId, channelId string) string {
+	return fmt.Sprintf("%s/%s.%s", sysapId, deviceId, channelId)
 }
 func (u *UnitData) getUnitMapKey() string {
-	return getUnitMapKey(u.SerialNumber, u.ChannelId)
+	return getUnitMapKey(u.SysApId, u.SerialNumber, u.ChannelId)
 }
 
-func getUnit(deviceId, channelId string) Unit {
-	key := getUnitMapKey(deviceId, channelId)
-	if unit, ok := UnitMap[key]; ok {
+func (c *Client) getUnit(sysapId, deviceId, channelId string) Unit {
+	key := getUnitMapKey(sysapId, deviceId, channelId)
+	if unit, ok := c.unitMap[key]; ok {
 		return unit
 	}
 	return nil
 }
 
-func PrtAllUnits() {
-	log.Println("------- BEGIN DUMP ALL UNITS")
-	keys := getUnitMapKeysSortedByFloorRoom()
+func (c *Client) PrtAllUnits() {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	c.logger.Println("------- BEGIN DUMP ALL UNITS")
+	keys := c.getUnitMapKeysSortedByFloorRoom()
 	for _, key := range keys {
-		log.Println(UnitMap[key].String())
+		c.logger.Println(c.unitMap[key].String())
 	}
-	log.Println("------- END DUMP ALL UNITS")
+	c.logger.Println("------- END DUMP ALL UNITS")
 }
 
 // Sort
@@ -87,16 +113,16 @@ func (u ByFloorAndRoom) Less(i, j int) bool {
 }
 func (u ByFloorAndRoom) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
 
-func getUnitMapKeysSortedByFloorRoom() []string {
-	copyArray := make([]Unit, len(UnitMap))
+func (c *Client) getUnitMapKeysSortedByFloorRoom() []string {
+	copyArray := make([]Unit, len(c.unitMap))
 	var i int = 0
-	for _, unit := range UnitMap {
+	for _, unit := range c.unitMap {
 		copyArray[i] = unit
 		i++
 	}
 	sort.Sort(ByFloorAndRoom(copyArray))
 
-	keys := make([]string, len(UnitMap))
+	keys := make([]string, len(c.unitMap))
 
 	i = 0
 	for _, unit := range copyArray {
@@ -109,7 +135,13 @@ func getUnitMapKeysSortedByFloorRoom() []string {
 
 // ####
 
-func GetFloorRoom(device *Device, channel *Channel) (string, string) {
+func (c *Client) GetFloorRoom(sysapId string, device *Device, channel *Channel) (string, string) {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	return c.getFloorRoom(sysapId, device, channel)
+}
+
+func (c *Client) getFloorRoom(sysapId string, device *Device, channel *Channel) (string, string) {
 	var floor, room string
 	var floorId, roomId string
 
@@ -124,11 +156,15 @@ func GetFloorRoom(device *Device, channel *Channel) (string, string) {
 	}
 	if channel.Room != nil {
 		roomId = *channel.Room
-	} else {
+	} else if device.Room != nil {
 		roomId = *device.Room
 	}
 
-	if floorObject, ok := SysAPConfiguration.Floorplan.Floors[floorId]; ok {
+	sysap, ok := c.sysAPConfiguration[sysapId]
+	if !ok {
+		return "", ""
+	}
+	if floorObject, ok := sysap.Floorplan.Floors[floorId]; ok {
 		floor = *floorObject.Name
 		if roomObject, ok := floorObject.Rooms[roomId]; ok {
 			room = *roomObject.Name
@@ -140,11 +176,12 @@ func GetFloorRoom(device *Device, channel *Channel) (string, string) {
 	return floor, room
 }
 
-func unitDataFactory(deviceId, channelId string, unitType UnitTypeConst) UnitData {
-	device := FreeDevices[deviceId]
-	floor, room := GetFloorRoom(device, device.Channels[channelId])
+func (c *Client) unitDataFactory(sysapId, deviceId, channelId string, unitType UnitTypeConst) UnitData {
+	device := c.freeDevices[sysapId][deviceId]
+	floor, room := c.getFloorRoom(sysapId, device, device.Channels[channelId])
 
 	return UnitData{
+		SysApId:      sysapId,
 		SerialNumber: deviceId,
 		NativeId:     device.NativeId,
 		ChannelId:    channelId,
@@ -153,73 +190,182 @@ func unitDataFactory(deviceId, channelId string, unitType UnitTypeConst) UnitDat
 		Floor:        floor,
 		Room:         room,
 		LastUpdate:   time.Now(),
+		client:       c,
 	}
 }
 
-func hydrateAllDevices(devices map[string]*Device) {
-	UnitMap = make(map[string]Unit, len(devices))
+// the caller has to hold the write lock of stateMutex
+func (c *Client) hydrateAllDevices(sysapDevices map[string]map[string]*Device) {
+	c.unitMap = make(map[string]Unit)
 
-	for deviceId, device := range devices {
-		hydrateDevice(deviceId, device)
+	for sysapId, devices := range sysapDevices {
+		for deviceId, device := range devices {
+			c.hydrateDevice(sysapId, deviceId, device)
+		}
 	}
-
-	treatAllUnitsAsUpdated(false) // initially handle all units as updated - e.g. send all to influx
 }
 
-var countTickRounds = 0
-
-func treatAllUnitsAsUpdated(forceLogging bool) {
-	if forceLogging || logLevel > 1 {
-		logger.Printf("------- BEGIN TREAD AS UNITS AS UPDATED --- %d ---\n", countTickRounds)
-	} else if logLevel > 0 {
-		logger.Printf("------- TICK EVENT %d - MARK ALL AS UPDATED\n", countTickRounds)
+func (c *Client) treatAllUnitsAsUpdated(forceLogging bool) {
+	if forceLogging || c.logLevel > 1 {
+		c.logger.Printf("------- BEGIN TREAD AS UNITS AS UPDATED --- %d ---\n", c.countTickRounds)
+	} else if c.logLevel > 0 {
+		c.logger.Printf("------- TICK EVENT %d - MARK ALL AS UPDATED\n", c.countTickRounds)
 	}
 
-	keys := getUnitMapKeysSortedByFloorRoom()
-	handleUpdatedUnits(keys, forceLogging || logLevel > 1)
+	c.stateMutex.RLock()
+	keys := c.getUnitMapKeysSortedByFloorRoom()
+	c.stateMutex.RUnlock()
+	c.handleUpdatedUnits(keys, forceLogging || c.logLevel > 1)
 
-	if logLevel > 1 {
-		logger.Printf("------- END TREAD AS UNITS AS UPDATED --- %d ---\n", countTickRounds)
+	if c.logLevel > 1 {
+		c.logger.Printf("------- END TREAD AS UNITS AS UPDATED --- %d ---\n", c.countTickRounds)
 	}
-	countTickRounds++
+	c.countTickRounds++
 }
 
-func handleUpdatedUnits(unitKeys []string, printDevices bool) {
-	if wsUpdateUnitCallback != nil {
-		wsUpdateUnitCallback(unitKeys) // tell someone what has changed
-	}
-
+// handleUpdatedUnits takes snapshots of the updated units, resets their changed flags and
+// calls the update callback with the snapshots. The caller must not hold stateMutex.
+func (c *Client) handleUpdatedUnits(unitKeys []string, printDevices bool) {
+	c.stateMutex.Lock()
+	snapshots := make([]Unit, 0, len(unitKeys))
 	for _, key := range unitKeys {
-		unit := UnitMap[key]
+		unit, ok := c.unitMap[key]
+		if !ok { // removed meanwhile
+			continue
+		}
 		if printDevices {
-			logger.Printf("%s\n", unit)
+			c.logger.Printf("%s\n", unit)
+		}
+		snapshot := unit.snapshot()
+		snapshots = append(snapshots, snapshot)
+		if before, ok := c.unitsBeforeChange[key]; ok {
+			c.queueEvent(&UnitChangedEvent{eventHead: newEventHead(), Key: key, Old: before, New: snapshot})
+			delete(c.unitsBeforeChange, key)
 		}
 		unit.resetChanged()
 	}
+	c.stateMutex.Unlock()
+
+	if c.wsUpdateUnitCallback != nil {
+		c.wsUpdateUnitCallback(snapshots) // tell someone what has changed
+	}
+	c.publishQueuedEvents()
 }
 
-func reHydrateUnitValue(deviceId string, channelId string, newData *InOutPut) (string, bool) {
-	key := getUnitMapKey(deviceId, channelId)
-	unit := UnitMap[key]
+func (c *Client) reHydrateUnitValue(sysapId string, deviceId string, channelId string, newData *InOutPut) (string, bool) {
+	key := getUnitMapKey(sysapId, deviceId, channelId)
+	unit := c.unitMap[key]
 	if unit == nil {
 		//fmt.Printf("reHydrateUnitValue: no unit found for key %s.\n", key)
 		return "", false
 	}
+	var before Unit
+	if c.hasSubscribers() {
+		before = unit.snapshot()
+	}
 	changed := unit.updateUnitFromOutDatapoint(newData)
+	c.queueUnitErrors(unit.GetUnitData())
 	if changed {
 		unit.GetUnitData().LastUpdate = time.Now()
+		if before != nil {
+			c.rememberUnitBeforeChange(key, before)
+		}
 	}
 	return key, changed
 }
 
-func hydrateDevice(deviceId string, device *Device) []string {
+// reHydrateUnitInput passes a changed input datapoint to the unit, if it follows its inputs.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) reHydrateUnitInput(sysapId string, deviceId string, channelId string, newData *InOutPut) (string, bool) {
+	key := getUnitMapKey(sysapId, deviceId, channelId)
+	unit, ok := c.unitMap[key].(inputUnit)
+	if !ok {
+		return "", false
+	}
+	var before Unit
+	if c.hasSubscribers() {
+		before = c.unitMap[key].snapshot()
+	}
+	changed := unit.updateUnitFromInDatapoint(newData)
+	c.queueUnitErrors(c.unitMap[key].GetUnitData())
+	if changed {
+		c.unitMap[key].GetUnitData().LastUpdate = time.Now()
+		if before != nil {
+			c.rememberUnitBeforeChange(key, before)
+		}
+	}
+	return key, changed
+}
+
+// decode decodes the value of a datapoint of the unit. Values which don't match their pairing id are skipped
+// and kept as error for queueUnitErrors. The caller has to hold the write lock of stateMutex.
+func (u *UnitData) decode(inOut *InOutPut) (DatapointValue, bool) {
+	if inOut.PairingID == nil || inOut.Value == nil {
+		return DatapointValue{}, false
+	}
+	value, err := DecodeValue(*inOut.PairingID, *inOut.Value)
+	if err != nil {
+		u.decodeErrors = append(u.decodeErrors, fmt.Errorf("unit %s: %w", u.getUnitMapKey(), err))
+		return value, false
+	}
+	return value, true
+}
+
+// queueUnitErrors reports the decode errors of the unit. The caller has to hold the write lock of stateMutex.
+func (c *Client) queueUnitErrors(u *UnitData) {
+	for _, err := range u.decodeErrors {
+		c.queueError(err)
+	}
+	u.decodeErrors = nil
+}
+
+// inputIdForPairing returns the id of the input datapoint of the unit's channel with the given pairing id.
+func (u *UnitData) inputIdForPairing(pairingId int) (string, bool) {
+	channel := u.GetChannel()
+	if channel == nil {
+		return "", false
+	}
+	for id, input := range channel.Inputs {
+		if input != nil && input.PairingID != nil && *input.PairingID == pairingId {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// putInput encodes the value (see EncodeValue) and writes it to the input datapoint of the unit's channel
+// with the given pairing id.
+func (u *UnitData) putInput(ctx context.Context, pairingId int, value interface{}) error {
+	if u.client == nil {
+		return fmt.Errorf("unit %s is not connected to a client", u.getUnitMapKey())
+	}
+	datapointId, ok := u.inputIdForPairing(pairingId)
+	if !ok {
+		return fmt.Errorf("unit %s has no input %s: %w", u.getUnitMapKey(), PairingIdName(pairingId), ErrNotFound)
+	}
+	raw, err := EncodeValue(pairingId, value)
+	if err != nil {
+		return err
+	}
+	accepted, err := u.client.PutDatapoint(ctx, u.SysApId, u.SerialNumber, u.ChannelId, datapointId, raw)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("SysAP didn't accept value %s for %s/%s", raw, u.getUnitMapKey(), datapointId)
+	}
+	return nil
+}
+
+func (c *Client) hydrateDevice(sysapId string, deviceId string, device *Device) []string {
 	var newUnitKeys []string
 	newUnitKeys = make([]string, 0, len(device.Channels))
 
 	for channelId := range device.Channels {
-		if unit := hydrateChannel(deviceId, device, channelId); unit != nil {
+		if unit := c.hydrateChannel(sysapId, deviceId, device, channelId); unit != nil {
+			c.queueUnitErrors(unit.GetUnitData())
 			key := unit.getUnitMapKey()
-			UnitMap[key] = unit
+			c.unitMap[key] = unit
 			newUnitKeys = append(newUnitKeys, key)
 		}
 	}
@@ -227,39 +373,57 @@ func hydrateDevice(deviceId string, device *Device) []string {
 	return newUnitKeys
 }
 
-func hydrateChannel(deviceId string, device *Device, channelId string) Unit {
-	switch FunctionIdType(*device.Channels[channelId].FunctionID) {
+func (c *Client) hydrateChannel(sysapId string, deviceId string, device *Device, channelId string) Unit {
+	channel := device.Channels[channelId]
+	if channel == nil {
+		return nil
+	}
+	if channel.FunctionID == nil {
+		return genericFactory(c, sysapId, deviceId, device, channelId)
+	}
+
+	switch FunctionIdType(*channel.FunctionID) {
 	case FID_SWITCH_SENSOR:
-		return switchSensorFactory(deviceId, device, channelId)
+		return switchSensorFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_DIMMING_SENSOR:
-		return dimmingSensorFactory(deviceId, device, channelId)
+		return dimmingSensorFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_SWITCH_ACTUATOR:
-		return switchActuatorFactory(deviceId, device, channelId)
+		return switchActuatorFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_DIMMING_ACTUATOR:
-		return dimmingActuatorFactory(deviceId, device, channelId)
+		return dimmingActuatorFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_WINDOW_DOOR_SENSOR:
-		return windowDoorSensorFactory(deviceId, device, channelId)
+		return windowDoorSensorFactory(c, sysapId, deviceId, device, channelId)
+
+	case FID_MOVEMENT_DETECTOR, FID_MOVEMENT_DETECTOR_ACTUATOR:
+		return movementDetectorFactory(c, sysapId, deviceId, device, channelId)
 
-	case FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITHOUT_FAN:
-		return roomTemperatureControllerFactory(deviceId, device, channelId)
+	case FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITHOUT_FAN, FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITH_FAN, FID_ROOM_TEMPERATURE_CONTROLLER_SLAVE:
+		return roomTemperatureControllerFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_BRIGHTNESS_SENSOR:
-		return weatherStationBrightnessFactory(deviceId, device, channelId)
+		return weatherStationBrightnessFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_RAIN_SENSOR:
-		return weatherStationRainFactory(deviceId, device, channelId)
+		return weatherStationRainFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_TEMPERATURE_SENSOR:
-		return weatherStationTemperatureFactory(deviceId, device, channelId)
+		return weatherStationTemperatureFactory(c, sysapId, deviceId, device, channelId)
 
 	case FID_WIND_SENSOR:
-		return weatherStationWindFactory(deviceId, device, channelId)
+		return weatherStationWindFactory(c, sysapId, deviceId, device, channelId)
+
+	case FID_SHUTTER_ACTUATOR, FID_ROLLER_BLIND_ACTUATOR, FID_ATTIC_WINDOW_ACTUATOR, FID_AWNING_ACTUATOR:
+		return shutterActuatorFactory(c, sysapId, deviceId, device, channelId)
+
+	case FID_HEATING_ACTUATOR, FID_FLOOR_HEATING_ACTUATOR, FID_RADIATOR_ACTUATOR, FID_UNDERFLOOR_HEATING:
+		return heatingActuatorFactory(c, sysapId, deviceId, device, channelId)
 
 	}
 
-	return nil
+	// all other channels are at least observable
+	return genericFactory(c, sysapId, deviceId, device, channelId)
 }
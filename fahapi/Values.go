@@ -0,0 +1,110 @@
+package fahapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DatapointValue is a datapoint value decoded according to the value type of its pairing id.
+// Numeric values are available as Float and as Int (rounded). Bool is true for all values but 0.
+type DatapointValue struct {
+	PairingId int
+	Type      ValueType // empty for unknown pairing ids
+	Raw       string
+	Bool      bool
+	Int       int
+	Float     float64
+}
+
+// DecodeValue decodes the raw value of a datapoint with the given pairing id. It returns a *ValueError, if the
+// value doesn't match the value type of the pairing id. Bool values have to be "0" or "1" (or another form
+// accepted by strconv.ParseBool), anything else is invalid. Values of unknown pairing ids are decoded as number,
+// if possible, and returned as Raw otherwise.
+func DecodeValue(pairingId int, raw string) (DatapointValue, error) {
+	value := DatapointValue{PairingId: pairingId, Raw: raw}
+	info, known := LookupPairingId(pairingId)
+	if known {
+		value.Type = info.ValueType
+	}
+
+	if value.Type == ValueBool {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return value, &ValueError{PairingId: pairingId, Value: raw, Reason: "no bool"}
+		}
+		value.Bool = b
+		if b {
+			value.Int, value.Float = 1, 1
+		}
+		return value, nil
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		if !known {
+			return value, nil
+		}
+		return value, &ValueError{PairingId: pairingId, Value: raw, Reason: "no number"}
+	}
+	value.Float = f
+	value.Int = int(math.Round(f))
+	value.Bool = f != 0
+
+	switch value.Type {
+	case ValuePercent:
+		if f < 0 || f > 100 {
+			return value, &ValueError{PairingId: pairingId, Value: raw, Reason: "percentage out of range 0..100"}
+		}
+	case ValueInt:
+		if f != math.Trunc(f) {
+			return value, &ValueError{PairingId: pairingId, Value: raw, Reason: "no integer"}
+		}
+	case ValueEnum:
+		if f != math.Trunc(f) || f < 0 {
+			return value, &ValueError{PairingId: pairingId, Value: raw, Reason: "no enum code"}
+		}
+	}
+	return value, nil
+}
+
+// Decode decodes the value of the datapoint, see DecodeValue.
+func (io *InOutPut) Decode() (DatapointValue, error) {
+	if io.PairingID == nil || io.Value == nil {
+		return DatapointValue{}, fmt.Errorf("datapoint without pairing id or value: %w", ErrInvalidValue)
+	}
+	return DecodeValue(*io.PairingID, *io.Value)
+}
+
+// EncodeValue validates a bool, int, float64 or string value against the value type of the pairing id and
+// encodes it for PutDatapoint.
+func EncodeValue(pairingId int, value interface{}) (string, error) {
+	var raw string
+	switch v := value.(type) {
+	case bool:
+		raw = "0"
+		if v {
+			raw = "1"
+		}
+	case int:
+		raw = strconv.Itoa(v)
+	case float64:
+		raw = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		raw = v
+	default:
+		return "", &ValueError{PairingId: pairingId, Value: fmt.Sprint(value), Reason: fmt.Sprintf("unsupported type %T", value)}
+	}
+
+	decoded, err := DecodeValue(pairingId, raw)
+	if err != nil {
+		return "", err
+	}
+	if decoded.Type == ValueBool { // send "true" etc. in the form of the SysAP
+		raw = "0"
+		if decoded.Bool {
+			raw = "1"
+		}
+	}
+	return raw, nil
+}
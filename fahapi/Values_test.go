@@ -0,0 +1,108 @@
+package fahapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeValue(t *testing.T) {
+	const unknownPairingId = 0xFFFE
+	tests := []struct {
+		pairingId int
+		raw       string
+		wantErr   bool
+		wantBool  bool
+		wantInt   int
+		wantFloat float64
+	}{
+		// bool
+		{AL_SWITCH_ON_OFF, "0", false, false, 0, 0},
+		{AL_SWITCH_ON_OFF, "1", false, true, 1, 1},
+		{AL_SWITCH_ON_OFF, "true", false, true, 1, 1},
+		{AL_SWITCH_ON_OFF, "2", true, false, 0, 0},
+		{AL_SWITCH_ON_OFF, "", true, false, 0, 0},
+		{AL_SWITCH_ON_OFF, "on", true, false, 0, 0},
+		// percent
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "0", false, false, 0, 0},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "100", false, true, 100, 100},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "45.6", false, true, 46, 45.6},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "-1", true, true, -1, -1},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "100.5", true, true, 101, 100.5},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, "50%", true, false, 0, 0},
+		// int
+		{AL_FAN_COIL_LEVEL, "3", false, true, 3, 3},
+		{AL_FAN_COIL_LEVEL, "-3", false, true, -3, -3},
+		{AL_FAN_COIL_LEVEL, "2.5", true, true, 3, 2.5},
+		{AL_FAN_COIL_LEVEL, "three", true, false, 0, 0},
+		// enum
+		{AL_FORCED, "0", false, false, 0, 0},
+		{AL_FORCED, "4", false, true, 4, 4},
+		{AL_FORCED, "-1", true, true, -1, -1},
+		{AL_FORCED, "1.5", true, true, 2, 1.5},
+		// float
+		{AL_SET_POINT_TEMPERATURE, "21.5", false, true, 22, 21.5},
+		{AL_SET_POINT_TEMPERATURE, "-4.25", false, true, -4, -4.25},
+		{AL_SET_POINT_TEMPERATURE, "warm", true, false, 0, 0},
+		// unknown pairing id
+		{unknownPairingId, "12", false, true, 12, 12},
+		{unknownPairingId, "text", false, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		value, err := DecodeValue(test.pairingId, test.raw)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s %q: error %v, want error %v", PairingIdName(test.pairingId), test.raw, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			var valueError *ValueError
+			if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &valueError) || valueError.PairingId != test.pairingId || valueError.Value != test.raw {
+				t.Errorf("%s %q: got error %#v, want a *ValueError", PairingIdName(test.pairingId), test.raw, err)
+			}
+			continue
+		}
+		if value.Raw != test.raw || value.Bool != test.wantBool || value.Int != test.wantInt || value.Float != test.wantFloat {
+			t.Errorf("%s %q: got %+v", PairingIdName(test.pairingId), test.raw, value)
+		}
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		pairingId int
+		value     interface{}
+		want      string // "" if the value has to be rejected
+	}{
+		{AL_SWITCH_ON_OFF, true, "1"},
+		{AL_SWITCH_ON_OFF, false, "0"},
+		{AL_SWITCH_ON_OFF, "true", "1"},
+		{AL_SWITCH_ON_OFF, 1, "1"},
+		{AL_SWITCH_ON_OFF, "yes", ""},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, 0, "0"},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, 100, "100"},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, 101, ""},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, -1, ""},
+		{AL_ABSOLUTE_SET_VALUE_CONTROL, 45.5, "45.5"},
+		{AL_FAN_COIL_LEVEL, 2, "2"},
+		{AL_FAN_COIL_LEVEL, 2.5, ""},
+		{AL_FORCED, 4, "4"},
+		{AL_FORCED, -1, ""},
+		{AL_SET_POINT_TEMPERATURE, 21.5, "21.5"},
+		{AL_SET_POINT_TEMPERATURE, "21.5", "21.5"},
+		{AL_SET_POINT_TEMPERATURE, "warm", ""},
+		{AL_SET_POINT_TEMPERATURE, []float64{21.5}, ""},
+	}
+
+	for _, test := range tests {
+		raw, err := EncodeValue(test.pairingId, test.value)
+		if test.want == "" {
+			if !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("%s %v: got %q, error %v, want ErrInvalidValue", PairingIdName(test.pairingId), test.value, raw, err)
+			}
+			continue
+		}
+		if err != nil || raw != test.want {
+			t.Errorf("%s %v: got %q, error %v, want %q", PairingIdName(test.pairingId), test.value, raw, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package fahapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A virtual device is operated by the SysAP like a real one: switching a virtual actuator on a wall panel
+// writes its inputs, and the SysAP expects the resulting state on the matching outputs. The typed virtual devices
+// (VirtualSwitchingActuator, VirtualDimActuator, VirtualRTC, ...) pass the commands to the registered handler funcs
+// and publish the state returned by the handler. Without a handler the command is acknowledged as it is.
+// The handlers can be passed to the constructors, so no command gets lost before a Handle... setter is called.
+// Every device handles its commands one after the other on its own goroutine, so a slow handler
+// doesn't block the websocket loop.
+// The Set... methods publish values of external sources. Values already published to the device are skipped,
+// until the manager PUTs the device again (Register or refresh), which may reset its outputs on the SysAP.
+
+// virtualCommand is an input of a virtual device written by the SysAP.
+type virtualCommand struct {
+	handler   virtualInputHandler
+	channelId string
+	inPut     InOutPut
+}
+
+// virtualCommandHandler handles the command written to an input and returns the state to publish on output.
+type virtualCommandHandler struct {
+	output int
+	handle func(ctx context.Context, value DatapointValue) (interface{}, error)
+}
+
+// queueVirtualCommand queues the input for the handler of the virtual device, if it is one of ours.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) queueVirtualCommand(sysapId string, deviceId string, channelId string, inPut *InOutPut) {
+	c.virtualMutex.Lock()
+	managers := c.virtualManagers
+	c.virtualMutex.Unlock()
+
+	for _, m := range managers {
+		if handler := m.handlerFor(sysapId, deviceId); handler != nil {
+			c.queuedCommands = append(c.queuedCommands, virtualCommand{handler: handler, channelId: channelId, inPut: *inPut})
+			return
+		}
+	}
+}
+
+// handleVirtualCommands passes the queued inputs to the virtual devices, which queue them for their worker.
+// The caller must not hold stateMutex.
+func (c *Client) handleVirtualCommands(ctx context.Context) {
+	c.stateMutex.Lock()
+	commands := c.queuedCommands
+	c.queuedCommands = nil
+	c.stateMutex.Unlock()
+
+	for _, command := range commands {
+		command.handler(ctx, command.channelId, &command.inPut)
+	}
+}
+
+// virtualDeviceHandle is the common part of the typed virtual devices.
+type virtualDeviceHandle struct {
+	manager      *VirtualDeviceManager
+	SysApId      string
+	NativeSerial string
+
+	mutex               sync.Mutex
+	commands            map[int]virtualCommandHandler // input pairing id -> handler
+	inputs              []queuedInput                 // commands waiting for the worker
+	working             bool                          // the worker goroutine is running
+	published           map[int]string                // output pairing id -> last value published
+	publishedGeneration uint64                        // registration the published values belong to
+}
+
+// queuedInput is a command of the SysAP waiting to be handled.
+type queuedInput struct {
+	ctx       context.Context
+	channelId string
+	inPut     InOutPut
+}
+
+// newVirtualDeviceHandle returns the handle of a virtual device, which isn't registered yet. Set the commands
+// before calling register, so the first commands of the SysAP reach their handlers.
+func (m *VirtualDeviceManager) newVirtualDeviceHandle(sysapId string, nativeSerial string) *virtualDeviceHandle {
+	return &virtualDeviceHandle{
+		manager:      m,
+		SysApId:      sysapId,
+		NativeSerial: nativeSerial,
+		commands:     make(map[int]virtualCommandHandler),
+		published:    make(map[int]string),
+	}
+}
+
+// register creates the device on the SysAP with the handle as input handler.
+func (v *virtualDeviceHandle) register(ctx context.Context, deviceType VirtualDeviceType, displayName string, ttl time.Duration) error {
+	_, err := v.manager.register(ctx, v.SysApId, v.NativeSerial, deviceType, displayName, ttl, v.handleInput)
+	return err
+}
+
+// DeviceId returns the device id the SysAP assigned to the virtual device.
+func (v *virtualDeviceHandle) DeviceId() string {
+	deviceId, _ := v.manager.DeviceId(v.SysApId, v.NativeSerial)
+	return deviceId
+}
+
+func (v *virtualDeviceHandle) setCommand(input int, output int, handle func(ctx context.Context, value DatapointValue) (interface{}, error)) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.commands[input] = virtualCommandHandler{output: output, handle: handle}
+}
+
+// handleInput queues a command for the worker of the device, starting the worker if it isn't running.
+// It doesn't block, so the websocket loop isn't held up by handlers or the PUT of their state.
+func (v *virtualDeviceHandle) handleInput(ctx context.Context, channelId string, inPut *InOutPut) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.inputs = append(v.inputs, queuedInput{ctx: ctx, channelId: channelId, inPut: *inPut})
+	if !v.working {
+		v.working = true
+		go v.work()
+	}
+}
+
+// work handles the queued commands in order and ends when the queue is empty.
+func (v *virtualDeviceHandle) work() {
+	for {
+		v.mutex.Lock()
+		if len(v.inputs) == 0 {
+			v.working = false
+			v.mutex.Unlock()
+			return
+		}
+		input := v.inputs[0]
+		v.inputs = v.inputs[1:]
+		v.mutex.Unlock()
+
+		v.runCommand(input.ctx, input.channelId, &input.inPut)
+	}
+}
+
+// runCommand passes a command to its handler and publishes the result. Errors are reported to the error
+// callback of the client, the state isn't published then.
+func (v *virtualDeviceHandle) runCommand(ctx context.Context, channelId string, inPut *InOutPut) {
+	if inPut.PairingID == nil || inPut.Value == nil {
+		return
+	}
+	v.mutex.Lock()
+	command, ok := v.commands[*inPut.PairingID]
+	v.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	c := v.manager.client
+	value, err := inPut.Decode()
+	if err != nil {
+		c.reportError(fmt.Errorf("virtual device %s: %w", v.NativeSerial, err))
+		return
+	}
+	var state interface{} = *inPut.Value
+	if command.handle != nil {
+		if state, err = command.handle(ctx, value); err != nil {
+			c.reportError(fmt.Errorf("virtual device %s: handling %s failed: %w", v.NativeSerial, PairingIdName(*inPut.PairingID), err))
+			return
+		}
+	}
+	// always acknowledge a command, even if the state didn't change
+	if err = v.publishChannel(ctx, channelId, command.output, state, true); err != nil {
+		c.reportError(err)
+	}
+}
+
+// publish writes the value to the output of the virtual device with the given pairing id, if it changed.
+func (v *virtualDeviceHandle) publish(ctx context.Context, pairingId int, value interface{}) error {
+	return v.publishChannel(ctx, "", pairingId, value, false)
+}
+
+// publishChannel writes the value to the output with the given pairing id, preferring the given channel.
+// Unless force is set, a value already published is skipped.
+func (v *virtualDeviceHandle) publishChannel(ctx context.Context, channelId string, pairingId int, value interface{}, force bool) error {
+	c := v.manager.client
+	deviceId, generation := v.manager.registration(v.SysApId, v.NativeSerial)
+	if deviceId == "" {
+		return fmt.Errorf("virtual device %s on SysAP %s is not registered: %w", v.NativeSerial, v.SysApId, ErrNotFound)
+	}
+	raw, err := EncodeValue(pairingId, value)
+	if err != nil {
+		return err
+	}
+	if published := v.alreadyPublished(generation, pairingId, raw); published && !force {
+		return nil
+	}
+
+	device := c.LookupDevice(v.SysApId, deviceId)
+	if device == nil { // not announced by the websocket yet
+		if device, err = c.GetDevice(ctx, v.SysApId, deviceId); err != nil {
+			return fmt.Errorf("can't load virtual device %s: %w", v.NativeSerial, err)
+		}
+	}
+	channelId, datapointId, ok := outputIdForPairing(device, channelId, pairingId)
+	if !ok {
+		return fmt.Errorf("virtual device %s has no output %s: %w", v.NativeSerial, PairingIdName(pairingId), ErrNotFound)
+	}
+
+	accepted, err := c.PutDatapoint(ctx, v.SysApId, deviceId, channelId, datapointId, raw)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("SysAP didn't accept value %s for virtual device %s %s.%s", raw, v.NativeSerial, channelId, datapointId)
+	}
+
+	v.mutex.Lock()
+	if v.publishedGeneration == generation {
+		v.published[pairingId] = raw
+	}
+	v.mutex.Unlock()
+	return nil
+}
+
+// alreadyPublished tells whether the value was the last one published to the output. The published values
+// are forgotten, if the device was PUT again since.
+func (v *virtualDeviceHandle) alreadyPublished(generation uint64, pairingId int, raw string) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.publishedGeneration != generation {
+		v.published = make(map[int]string)
+		v.publishedGeneration = generation
+	}
+	last, ok := v.published[pairingId]
+	return ok && last == raw
+}
+
+// outputIdForPairing returns channel and datapoint id of the output with the given pairing id.
+// If channelId is set, that channel is searched first.
+func outputIdForPairing(device *Device, channelId string, pairingId int) (string, string, bool) {
+	find := func(channel *Channel) (string, bool) {
+		if channel == nil {
+			return "", false
+		}
+		for id, output := range channel.Outputs {
+			if output != nil && output.PairingID != nil && *output.PairingID == pairingId {
+				return id, true
+			}
+		}
+		return "", false
+	}
+
+	if datapointId, ok := find(device.Channels[channelId]); ok {
+		return channelId, datapointId, true
+	}
+	for id, channel := range device.Channels {
+		if datapointId, ok := find(channel); ok {
+			return id, datapointId, true
+		}
+	}
+	return "", "", false
+}
+
+// =========================================================================================
+
+// boolCommand, intCommand and floatCommand adapt typed handlers to setCommand. A nil handler acknowledges
+// the command as it is.
+func boolCommand(handler func(ctx context.Context, on bool) (bool, error)) func(ctx context.Context, value DatapointValue) (interface{}, error) {
+	if handler == nil {
+		return nil
+	}
+	return func(ctx context.Context, value DatapointValue) (interface{}, error) {
+		return handler(ctx, value.Bool)
+	}
+}
+
+func intCommand(handler func(ctx context.Context, value int) (int, error)) func(ctx context.Context, value DatapointValue) (interface{}, error) {
+	if handler == nil {
+		return nil
+	}
+	return func(ctx context.Context, value DatapointValue) (interface{}, error) {
+		return handler(ctx, value.Int)
+	}
+}
+
+func floatCommand(handler func(ctx context.Context, value float64) (float64, error)) func(ctx context.Context, value DatapointValue) (interface{}, error) {
+	if handler == nil {
+		return nil
+	}
+	return func(ctx context.Context, value DatapointValue) (interface{}, error) {
+		return handler(ctx, value.Float)
+	}
+}
+
+// VirtualSwitchingActuator is a virtual switch actuator. Switching it on the SysAP calls the Switch handler,
+// the resulting state is published to AL_INFO_ON_OFF.
+type VirtualSwitchingActuator struct {
+	*virtualDeviceHandle
+}
+
+// VirtualSwitchingActuatorHandlers are the command handlers of a VirtualSwitchingActuator (see HandleSwitch).
+// Nil handlers acknowledge the command as it is.
+type VirtualSwitchingActuatorHandlers struct {
+	Switch func(ctx context.Context, on bool) (bool, error)
+}
+
+// NewVirtualSwitchingActuator registers a virtual switch actuator with its handlers (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualSwitchingActuator(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualSwitchingActuatorHandlers) (*VirtualSwitchingActuator, error) {
+	v := &VirtualSwitchingActuator{m.newVirtualDeviceHandle(sysapId, nativeSerial)}
+	v.HandleSwitch(handlers.Switch)
+	if err := v.register(ctx, VirtualDeviceType_SwitchingActuator, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HandleSwitch sets the handler for AL_SWITCH_ON_OFF. It returns the resulting state.
+func (v *VirtualSwitchingActuator) HandleSwitch(handler func(ctx context.Context, on bool) (bool, error)) {
+	v.setCommand(AL_SWITCH_ON_OFF, AL_INFO_ON_OFF, boolCommand(handler))
+}
+
+// SetOn publishes the state, e.g. if the device was switched by other means than the SysAP.
+func (v *VirtualSwitchingActuator) SetOn(ctx context.Context, on bool) error {
+	return v.publish(ctx, AL_INFO_ON_OFF, on)
+}
+
+// VirtualRTC is a virtual room temperature controller. Set point, on/off and eco requests of the SysAP
+// call the handlers, the resulting state is published to AL_SET_POINT_TEMPERATURE, AL_CONTROLLER_ON_OFF
+// and AL_ECO_ON_OFF.
+type VirtualRTC struct {
+	*virtualDeviceHandle
+}
+
+// VirtualRTCHandlers are the command handlers of a VirtualRTC (see HandleSetPoint, HandleOnOff and HandleEco).
+// Nil handlers acknowledge the command as it is.
+type VirtualRTCHandlers struct {
+	SetPoint func(ctx context.Context, degree float64) (float64, error)
+	OnOff    func(ctx context.Context, on bool) (bool, error)
+	Eco      func(ctx context.Context, eco bool) (bool, error)
+}
+
+// NewVirtualRTC registers a virtual room temperature controller with its handlers (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualRTC(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualRTCHandlers) (*VirtualRTC, error) {
+	v := &VirtualRTC{m.newVirtualDeviceHandle(sysapId, nativeSerial)}
+	v.HandleSetPoint(handlers.SetPoint)
+	v.HandleOnOff(handlers.OnOff)
+	v.HandleEco(handlers.Eco)
+	if err := v.register(ctx, VirtualDeviceType_RTC, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HandleSetPoint sets the handler for AL_ABSOLUTE_SET_POINT_REQUEST. It returns the resulting set point.
+func (v *VirtualRTC) HandleSetPoint(handler func(ctx context.Context, degree float64) (float64, error)) {
+	v.setCommand(AL_ABSOLUTE_SET_POINT_REQUEST, AL_SET_POINT_TEMPERATURE, floatCommand(handler))
+}
+
+// HandleOnOff sets the handler for AL_CONTROLLER_ON_OFF_REQUEST. It returns the resulting state.
+func (v *VirtualRTC) HandleOnOff(handler func(ctx context.Context, on bool) (bool, error)) {
+	v.setCommand(AL_CONTROLLER_ON_OFF_REQUEST, AL_CONTROLLER_ON_OFF, boolCommand(handler))
+}
+
+// HandleEco sets the handler for AL_ECO_ON_OFF. It returns the resulting state.
+func (v *VirtualRTC) HandleEco(handler func(ctx context.Context, eco bool) (bool, error)) {
+	v.setCommand(AL_ECO_ON_OFF, AL_ECO_ON_OFF, boolCommand(handler))
+}
+
+// SetMeasuredTemperature publishes the room temperature (AL_MEASURED_TEMPERATURE).
+func (v *VirtualRTC) SetMeasuredTemperature(ctx context.Context, degree float64) error {
+	return v.publish(ctx, AL_MEASURED_TEMPERATURE, degree)
+}
+
+// SetSetPoint publishes the set point (AL_SET_POINT_TEMPERATURE), e.g. if it was changed on the device itself.
+func (v *VirtualRTC) SetSetPoint(ctx context.Context, degree float64) error {
+	return v.publish(ctx, AL_SET_POINT_TEMPERATURE, degree)
+}
+
+// SetOn publishes the on/off state of the controller (AL_CONTROLLER_ON_OFF).
+func (v *VirtualRTC) SetOn(ctx context.Context, on bool) error {
+	return v.publish(ctx, AL_CONTROLLER_ON_OFF, on)
+}
+
+// SetEco publishes the eco state of the controller (AL_ECO_ON_OFF).
+func (v *VirtualRTC) SetEco(ctx context.Context, eco bool) error {
+	return v.publish(ctx, AL_ECO_ON_OFF, eco)
+}
+
+// VirtualDimActuator is a virtual dimming actuator. The resulting state is published to AL_INFO_ON_OFF
+// and AL_INFO_ACTUAL_DIMMING_VALUE.
+type VirtualDimActuator struct {
+	*virtualDeviceHandle
+}
+
+// VirtualDimActuatorHandlers are the command handlers of a VirtualDimActuator (see HandleSwitch and
+// HandleBrightness). Nil handlers acknowledge the command as it is.
+type VirtualDimActuatorHandlers struct {
+	Switch     func(ctx context.Context, on bool) (bool, error)
+	Brightness func(ctx context.Context, percent int) (int, error)
+}
+
+// NewVirtualDimActuator registers a virtual dimming actuator with its handlers (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualDimActuator(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualDimActuatorHandlers) (*VirtualDimActuator, error) {
+	v := &VirtualDimActuator{m.newVirtualDeviceHandle(sysapId, nativeSerial)}
+	v.HandleSwitch(handlers.Switch)
+	v.HandleBrightness(handlers.Brightness)
+	if err := v.register(ctx, VirtualDeviceType_DimActuator, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HandleSwitch sets the handler for AL_SWITCH_ON_OFF. It returns the resulting state.
+func (v *VirtualDimActuator) HandleSwitch(handler func(ctx context.Context, on bool) (bool, error)) {
+	v.setCommand(AL_SWITCH_ON_OFF, AL_INFO_ON_OFF, boolCommand(handler))
+}
+
+// HandleBrightness sets the handler for AL_ABSOLUTE_SET_VALUE_CONTROL. It returns the resulting brightness in percent.
+func (v *VirtualDimActuator) HandleBrightness(handler func(ctx context.Context, percent int) (int, error)) {
+	v.setCommand(AL_ABSOLUTE_SET_VALUE_CONTROL, AL_INFO_ACTUAL_DIMMING_VALUE, intCommand(handler))
+}
+
+// SetOn publishes the on/off state (AL_INFO_ON_OFF).
+func (v *VirtualDimActuator) SetOn(ctx context.Context, on bool) error {
+	return v.publish(ctx, AL_INFO_ON_OFF, on)
+}
+
+// SetBrightness publishes the brightness in percent (AL_INFO_ACTUAL_DIMMING_VALUE).
+func (v *VirtualDimActuator) SetBrightness(ctx context.Context, percent int) error {
+	return v.publish(ctx, AL_INFO_ACTUAL_DIMMING_VALUE, percent)
+}
+
+// VirtualCeilingFanActuator is a virtual ceiling fan. The resulting state is published to AL_INFO_ON_OFF
+// and AL_ABSOLUTE_FAN_SPEED_CONTROL.
+type VirtualCeilingFanActuator struct {
+	*virtualDeviceHandle
+}
+
+// VirtualCeilingFanActuatorHandlers are the command handlers of a VirtualCeilingFanActuator (see HandleSwitch
+// and HandleSpeed). Nil handlers acknowledge the command as it is.
+type VirtualCeilingFanActuatorHandlers struct {
+	Switch func(ctx context.Context, on bool) (bool, error)
+	Speed  func(ctx context.Context, percent int) (int, error)
+}
+
+// NewVirtualCeilingFanActuator registers a virtual ceiling fan with its handlers (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualCeilingFanActuator(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualCeilingFanActuatorHandlers) (*VirtualCeilingFanActuator, error) {
+	v := &VirtualCeilingFanActuator{m.newVirtualDeviceHandle(sysapId, nativeSerial)}
+	v.HandleSwitch(handlers.Switch)
+	v.HandleSpeed(handlers.Speed)
+	if err := v.register(ctx, VirtualDeviceType_CeilingFanActuator, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HandleSwitch sets the handler for AL_SWITCH_ON_OFF. It returns the resulting state.
+func (v *VirtualCeilingFanActuator) HandleSwitch(handler func(ctx context.Context, on bool) (bool, error)) {
+	v.setCommand(AL_SWITCH_ON_OFF, AL_INFO_ON_OFF, boolCommand(handler))
+}
+
+// HandleSpeed sets the handler for AL_ABSOLUTE_FAN_SPEED_CONTROL. It returns the resulting speed in percent.
+func (v *VirtualCeilingFanActuator) HandleSpeed(handler func(ctx context.Context, percent int) (int, error)) {
+	v.setCommand(AL_ABSOLUTE_FAN_SPEED_CONTROL, AL_ABSOLUTE_FAN_SPEED_CONTROL, intCommand(handler))
+}
+
+// SetOn publishes the on/off state (AL_INFO_ON_OFF).
+func (v *VirtualCeilingFanActuator) SetOn(ctx context.Context, on bool) error {
+	return v.publish(ctx, AL_INFO_ON_OFF, on)
+}
+
+// SetSpeed publishes the speed in percent (AL_ABSOLUTE_FAN_SPEED_CONTROL).
+func (v *VirtualCeilingFanActuator) SetSpeed(ctx context.Context, percent int) error {
+	return v.publish(ctx, AL_ABSOLUTE_FAN_SPEED_CONTROL, percent)
+}
+
+// virtualPositionActuator is the common part of virtual shutters and windows. Positions are percentages,
+// 0 = open, 100 = closed. Move and position commands publish the resulting position to
+// AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, a stop publishes ShutterNotMoving to AL_INFO_MOVE_UP_DOWN.
+type virtualPositionActuator struct {
+	*virtualDeviceHandle
+}
+
+// VirtualPositionActuatorHandlers are the command handlers of virtual shutters and windows (see HandlePosition,
+// HandleMove, HandleStop and HandleSlatPosition). Nil handlers get the defaults of the setters.
+type VirtualPositionActuatorHandlers struct {
+	Position     func(ctx context.Context, percent int) (int, error)
+	Move         func(ctx context.Context, down bool) (int, error)
+	Stop         func(ctx context.Context) error
+	SlatPosition func(ctx context.Context, percent int) (int, error) // shutters only
+}
+
+func newVirtualPositionActuator(v *virtualDeviceHandle, handlers VirtualPositionActuatorHandlers) virtualPositionActuator {
+	a := virtualPositionActuator{v}
+	a.HandlePosition(handlers.Position)
+	a.HandleMove(handlers.Move)
+	a.HandleStop(handlers.Stop)
+	return a
+}
+
+// HandlePosition sets the handler for AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE. It returns the resulting position.
+// Without a handler the requested position is published.
+func (v virtualPositionActuator) HandlePosition(handler func(ctx context.Context, percent int) (int, error)) {
+	v.setCommand(AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, intCommand(handler))
+}
+
+// HandleMove sets the handler for AL_MOVE_UP_DOWN (down = close). It returns the resulting position.
+// Without a handler the position is 0 after moving up and 100 after moving down.
+func (v virtualPositionActuator) HandleMove(handler func(ctx context.Context, down bool) (int, error)) {
+	if handler == nil {
+		handler = func(ctx context.Context, down bool) (int, error) {
+			if down {
+				return 100, nil
+			}
+			return 0, nil
+		}
+	}
+	v.setCommand(AL_MOVE_UP_DOWN, AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, func(ctx context.Context, value DatapointValue) (interface{}, error) {
+		return handler(ctx, value.Bool)
+	})
+}
+
+// HandleStop sets the handler for AL_STOP_STEP_UP_DOWN. Publish the position reached with SetPosition.
+// Without a handler a stop just publishes ShutterNotMoving.
+func (v virtualPositionActuator) HandleStop(handler func(ctx context.Context) error) {
+	v.setCommand(AL_STOP_STEP_UP_DOWN, AL_INFO_MOVE_UP_DOWN, func(ctx context.Context, value DatapointValue) (interface{}, error) {
+		if handler == nil {
+			return ShutterNotMoving, nil
+		}
+		return ShutterNotMoving, handler(ctx)
+	})
+}
+
+// SetPosition publishes the position in percent (AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE).
+func (v virtualPositionActuator) SetPosition(ctx context.Context, percent int) error {
+	return v.publish(ctx, AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, percent)
+}
+
+// SetMoveState publishes the movement (AL_INFO_MOVE_UP_DOWN, see ShutterNotMoving etc.).
+func (v virtualPositionActuator) SetMoveState(ctx context.Context, state int) error {
+	return v.publish(ctx, AL_INFO_MOVE_UP_DOWN, state)
+}
+
+// VirtualShutterActuator is a virtual shutter or blind actuator.
+type VirtualShutterActuator struct {
+	virtualPositionActuator
+}
+
+// NewVirtualShutterActuator registers a virtual shutter actuator with its handlers (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualShutterActuator(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualPositionActuatorHandlers) (*VirtualShutterActuator, error) {
+	v := &VirtualShutterActuator{newVirtualPositionActuator(m.newVirtualDeviceHandle(sysapId, nativeSerial), handlers)}
+	v.HandleSlatPosition(handlers.SlatPosition)
+	if err := v.register(ctx, VirtualDeviceType_ShutterActuator, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HandleSlatPosition sets the handler for AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE. It returns the resulting position.
+func (v *VirtualShutterActuator) HandleSlatPosition(handler func(ctx context.Context, percent int) (int, error)) {
+	v.setCommand(AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE, AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE, intCommand(handler))
+}
+
+// SetSlatPosition publishes the slat position in percent (AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE).
+func (v *VirtualShutterActuator) SetSlatPosition(ctx context.Context, percent int) error {
+	return v.publish(ctx, AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE, percent)
+}
+
+// VirtualWindowActuator is a virtual window actuator.
+type VirtualWindowActuator struct {
+	virtualPositionActuator
+}
+
+// NewVirtualWindowActuator registers a virtual window actuator with its handlers (see Register for the ttl).
+// The SlatPosition handler is not used.
+func (m *VirtualDeviceManager) NewVirtualWindowActuator(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration, handlers VirtualPositionActuatorHandlers) (*VirtualWindowActuator, error) {
+	v := &VirtualWindowActuator{newVirtualPositionActuator(m.newVirtualDeviceHandle(sysapId, nativeSerial), handlers)}
+	if err := v.register(ctx, VirtualDeviceType_WindowActuator, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,69 @@
+package fahapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVirtualCommandsDontBlockWebsocket(t *testing.T) {
+	virtualKey := getUnitMapKey(testSysAP, "6000SWITCH", "ch0000")
+	realKey := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+	f := newFakeSysAP(t, map[string]*Device{
+		"6000SWITCH":   testSwitch("Virtual", "0"),
+		"ABB700000001": testSwitch("Lamp", "0"),
+	})
+	c := startClient(t, f)
+	ctx := testContext(t)
+
+	var mutex sync.Mutex
+	var commands []bool
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	m := c.NewVirtualDeviceManager()
+	handlers := VirtualSwitchingActuatorHandlers{Switch: func(ctx context.Context, on bool) (bool, error) {
+		mutex.Lock()
+		commands = append(commands, on)
+		mutex.Unlock()
+		started <- struct{}{}
+		<-release
+		return on, nil
+	}}
+	v, err := m.NewVirtualSwitchingActuator(ctx, testSysAP, "switch", "Virtual", -1, handlers)
+	if err != nil || v.DeviceId() != "6000SWITCH" {
+		t.Fatalf("NewVirtualSwitchingActuator: %v, %v", v, err)
+	}
+
+	conn := startLoop(t, f, c)
+	sendDatapoints(t, conn, map[string]string{"6000SWITCH/ch0000/idp0000": "1"})
+	<-started
+	sendDatapoints(t, conn, map[string]string{"6000SWITCH/ch0000/idp0000": "0"})
+
+	// the handler blocks, the websocket updates go on
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "1"})
+	waitFor(t, "update of the real device", func() bool { return CastSAU(c.LookupUnit(realKey)).On })
+	if c.LookupUnit(virtualKey) == nil {
+		t.Fatal("virtual device isn't hydrated")
+	}
+
+	close(release)
+	statePuts := func() []string {
+		var values []string
+		for _, put := range f.recordedPuts() {
+			if strings.HasSuffix(put.Path, "/6000SWITCH.ch0000.odp0000") {
+				values = append(values, put.Body)
+			}
+		}
+		return values
+	}
+	waitFor(t, "acknowledges", func() bool { return len(statePuts()) == 2 })
+	if values := statePuts(); values[0] != "1" || values[1] != "0" {
+		t.Errorf("published states %v, want [1 0]", values)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(commands) != 2 || !commands[0] || commands[1] {
+		t.Errorf("handled commands %v, want [true false]", commands)
+	}
+}
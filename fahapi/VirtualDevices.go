@@ -0,0 +1,380 @@
+package fahapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultVirtualDeviceTTL is used by VirtualDeviceManager.Register, if no TTL is given.
+const DefaultVirtualDeviceTTL = 5 * time.Minute
+
+// virtual devices are refreshed after this fraction of their TTL, failed refreshes are retried after virtualDeviceRetry
+const virtualDeviceRefreshFraction = 2
+const virtualDeviceRetry = 10 * time.Second
+
+// ManagedVirtualDevice is a virtual device kept alive by a VirtualDeviceManager.
+type ManagedVirtualDevice struct {
+	SysApId      string
+	NativeSerial string // serial chosen by us
+	DeviceId     string // serial of the device assigned by the SysAP
+	Type         VirtualDeviceType
+	DisplayName  string
+	TTL          time.Duration // 0 = the device never expires
+	LastRefresh  time.Time
+
+	nextRefresh time.Time
+	generation  uint64              // changes with every successful PUT, see registration
+	handler     virtualInputHandler // handles the inputs written by the SysAP, see VirtualSwitchingActuator etc.
+}
+
+// virtualInputHandler gets the inputs of a virtual device written by the SysAP (e.g. switched via a wall panel).
+type virtualInputHandler func(ctx context.Context, channelId string, inPut *InOutPut)
+
+// VirtualDeviceManager creates virtual devices on the SysAPs of a client and re-PUTs them before their TTL
+// runs out, so they don't vanish. It tracks the mapping from our native serials to the device ids of the SysAP.
+type VirtualDeviceManager struct {
+	client *Client
+
+	mutex       sync.Mutex
+	devices     map[string]*ManagedVirtualDevice // <sysapId>/<nativeSerial> -> device
+	generations uint64                           // last generation assigned to a device
+	wakeup      chan struct{}                    // tells Run about changed refresh times
+
+	loopMutex sync.Mutex
+	stopLoop  context.CancelFunc
+}
+
+// NewVirtualDeviceManager creates a manager for virtual devices. Call Run to keep the devices alive.
+func (c *Client) NewVirtualDeviceManager() *VirtualDeviceManager {
+	m := &VirtualDeviceManager{
+		client:  c,
+		devices: make(map[string]*ManagedVirtualDevice),
+		wakeup:  make(chan struct{}, 1),
+	}
+	c.virtualMutex.Lock()
+	c.virtualManagers = append(c.virtualManagers, m)
+	c.virtualMutex.Unlock()
+	return m
+}
+
+func virtualDeviceKey(sysapId string, nativeSerial string) string {
+	return sysapId + "/" + nativeSerial
+}
+
+// ttlString returns the ttl in seconds as expected by the SysAP, -1 = never expires
+func ttlString(ttl time.Duration) string {
+	if ttl <= 0 {
+		return "-1"
+	}
+	seconds := int(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// Register creates the virtual device on the SysAP and keeps it alive from now on. A ttl of 0 uses
+// DefaultVirtualDeviceTTL, a negative ttl creates a device which never expires. Registering a known serial again
+// updates type, name and ttl. Returns the device id assigned by the SysAP.
+func (m *VirtualDeviceManager) Register(ctx context.Context, sysapId string, nativeSerial string, deviceType VirtualDeviceType, displayName string, ttl time.Duration) (string, error) {
+	return m.register(ctx, sysapId, nativeSerial, deviceType, displayName, ttl, nil)
+}
+
+// register is Register with the input handler of the device, nil keeps the handler of a known serial.
+func (m *VirtualDeviceManager) register(ctx context.Context, sysapId string, nativeSerial string, deviceType VirtualDeviceType, displayName string, ttl time.Duration, handler virtualInputHandler) (string, error) {
+	if ttl == 0 {
+		ttl = DefaultVirtualDeviceTTL
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	device := &ManagedVirtualDevice{
+		SysApId:      sysapId,
+		NativeSerial: nativeSerial,
+		Type:         deviceType,
+		DisplayName:  displayName,
+		TTL:          ttl,
+		handler:      handler,
+	}
+	if err := m.put(ctx, device); err != nil {
+		return "", err
+	}
+
+	key := virtualDeviceKey(sysapId, nativeSerial)
+	m.mutex.Lock()
+	if registered, ok := m.devices[key]; ok && handler == nil {
+		device.handler = registered.handler
+	}
+	m.generations++
+	device.generation = m.generations
+	m.devices[key] = device
+	m.mutex.Unlock()
+	m.wakeUp()
+
+	return device.DeviceId, nil
+}
+
+// Unregister stops refreshing the virtual device. If remove is true, it is deleted on the SysAP (by a PUT with ttl 0),
+// otherwise it vanishes when its TTL runs out.
+func (m *VirtualDeviceManager) Unregister(ctx context.Context, sysapId string, nativeSerial string, remove bool) error {
+	key := virtualDeviceKey(sysapId, nativeSerial)
+	m.mutex.Lock()
+	device, ok := m.devices[key]
+	delete(m.devices, key)
+	m.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown virtual device %s on SysAP %s: %w", nativeSerial, sysapId, ErrNotFound)
+	}
+	if !remove {
+		return nil
+	}
+	return m.remove(ctx, device)
+}
+
+// DeviceId returns the device id the SysAP assigned to the virtual device with our native serial.
+func (m *VirtualDeviceManager) DeviceId(sysapId string, nativeSerial string) (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if device, ok := m.devices[virtualDeviceKey(sysapId, nativeSerial)]; ok {
+		return device.DeviceId, true
+	}
+	return "", false
+}
+
+// NativeSerial returns our native serial of the virtual device with the given device id of the SysAP.
+func (m *VirtualDeviceManager) NativeSerial(sysapId string, deviceId string) (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, device := range m.devices {
+		if device.SysApId == sysapId && device.DeviceId == deviceId {
+			return device.NativeSerial, true
+		}
+	}
+	return "", false
+}
+
+// registration returns the device id and the generation of the virtual device. The generation changes with every
+// successful PUT of the device, which may reset its outputs on the SysAP.
+func (m *VirtualDeviceManager) registration(sysapId string, nativeSerial string) (string, uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if device, ok := m.devices[virtualDeviceKey(sysapId, nativeSerial)]; ok {
+		return device.DeviceId, device.generation
+	}
+	return "", 0
+}
+
+// handlerFor returns the input handler of the virtual device with the given device id of the SysAP.
+func (m *VirtualDeviceManager) handlerFor(sysapId string, deviceId string) virtualInputHandler {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, device := range m.devices {
+		if device.SysApId == sysapId && device.DeviceId == deviceId {
+			return device.handler
+		}
+	}
+	return nil
+}
+
+// Devices returns copies of all managed virtual devices, sorted by SysAP and native serial.
+func (m *VirtualDeviceManager) Devices() []ManagedVirtualDevice {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	devices := make([]ManagedVirtualDevice, 0, len(m.devices))
+	for _, device := range m.devices {
+		devices = append(devices, *device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return virtualDeviceKey(devices[i].SysApId, devices[i].NativeSerial) < virtualDeviceKey(devices[j].SysApId, devices[j].NativeSerial)
+	})
+	return devices
+}
+
+// Run refreshes the virtual devices before their TTL runs out, until ctx is cancelled or Stop is called.
+// Failed refreshes are reported to the error callback of the client and retried.
+func (m *VirtualDeviceManager) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m.loopMutex.Lock()
+	if m.stopLoop != nil {
+		m.loopMutex.Unlock()
+		return fmt.Errorf("virtual device loop is already running")
+	}
+	m.stopLoop = cancel
+	m.loopMutex.Unlock()
+
+	defer func() {
+		m.loopMutex.Lock()
+		m.stopLoop = nil
+		m.loopMutex.Unlock()
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			m.refreshDue(ctx)
+		case <-m.wakeup:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		timer.Reset(m.untilNextRefresh())
+	}
+}
+
+// Stop ends Run. The devices are neither refreshed nor deleted anymore, use Shutdown for that.
+func (m *VirtualDeviceManager) Stop() {
+	m.loopMutex.Lock()
+	defer m.loopMutex.Unlock()
+	if m.stopLoop != nil {
+		m.stopLoop()
+	}
+}
+
+// Shutdown stops Run and unregisters all devices. If remove is true, they are deleted on the SysAP,
+// otherwise they vanish when their TTL runs out. Returns the first error of the deletes.
+// The manager no longer receives commands afterwards, create a new one to register devices again.
+func (m *VirtualDeviceManager) Shutdown(ctx context.Context, remove bool) error {
+	m.Stop()
+
+	c := m.client
+	c.virtualMutex.Lock()
+	managers := make([]*VirtualDeviceManager, 0, len(c.virtualManagers))
+	for _, other := range c.virtualManagers {
+		if other != m {
+			managers = append(managers, other)
+		}
+	}
+	c.virtualManagers = managers // a new slice, queueVirtualCommand may still iterate over the old one
+	c.virtualMutex.Unlock()
+
+	m.mutex.Lock()
+	devices := m.devices
+	m.devices = make(map[string]*ManagedVirtualDevice)
+	m.mutex.Unlock()
+
+	if !remove {
+		return nil
+	}
+	var firstErr error
+	for _, device := range devices {
+		if err := m.remove(ctx, device); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *VirtualDeviceManager) wakeUp() {
+	select {
+	case m.wakeup <- struct{}{}:
+	default: // a wakeup is already pending
+	}
+}
+
+// put creates or refreshes the device on the SysAP and schedules its next refresh.
+func (m *VirtualDeviceManager) put(ctx context.Context, device *ManagedVirtualDevice) error {
+	message := &VirtualDevice{
+		Type:       device.Type,
+		Properties: VirtualDeviceProperties{Displayname: device.DisplayName, Ttl: ttlString(device.TTL)},
+	}
+	deviceId, err := m.client.PutVirtualDevice(ctx, device.SysApId, device.NativeSerial, message)
+	if err != nil {
+		return fmt.Errorf("can't put virtual device %s on SysAP %s: %w", device.NativeSerial, device.SysApId, err)
+	}
+
+	if device.DeviceId != "" && device.DeviceId != deviceId && m.client.logLevel > 0 {
+		m.client.logger.Printf("Virtual device %s on SysAP %s got new device id %s (was %s)\n", device.NativeSerial, device.SysApId, deviceId, device.DeviceId)
+	}
+	device.DeviceId = deviceId
+	device.LastRefresh = time.Now()
+	device.nextRefresh = time.Time{}
+	if device.TTL > 0 {
+		device.nextRefresh = device.LastRefresh.Add(device.TTL / virtualDeviceRefreshFraction)
+	}
+	return nil
+}
+
+func (m *VirtualDeviceManager) remove(ctx context.Context, device *ManagedVirtualDevice) error {
+	message := &VirtualDevice{
+		Type:       device.Type,
+		Properties: VirtualDeviceProperties{Displayname: device.DisplayName, Ttl: "0"},
+	}
+	if _, err := m.client.PutVirtualDevice(ctx, device.SysApId, device.NativeSerial, message); err != nil {
+		return fmt.Errorf("can't delete virtual device %s on SysAP %s: %w", device.NativeSerial, device.SysApId, err)
+	}
+	return nil
+}
+
+// refreshDue re-PUTs all devices whose refresh is due. The REST calls are done without holding the mutex,
+// devices unregistered meanwhile are not scheduled again.
+func (m *VirtualDeviceManager) refreshDue(ctx context.Context) {
+	now := time.Now()
+	m.mutex.Lock()
+	var due []ManagedVirtualDevice
+	for _, device := range m.devices {
+		if !device.nextRefresh.IsZero() && !device.nextRefresh.After(now) {
+			due = append(due, *device)
+		}
+	}
+	m.mutex.Unlock()
+
+	for i := range due {
+		device := &due[i]
+		err := m.put(ctx, device)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.client.reportError(err)
+			device.nextRefresh = time.Now().Add(virtualDeviceRetry)
+		}
+
+		key := virtualDeviceKey(device.SysApId, device.NativeSerial)
+		m.mutex.Lock()
+		if current, ok := m.devices[key]; ok && current.Type == device.Type && current.TTL == device.TTL {
+			if err == nil {
+				current.DeviceId = device.DeviceId
+				current.LastRefresh = device.LastRefresh
+				m.generations++
+				current.generation = m.generations
+			}
+			current.nextRefresh = device.nextRefresh
+		}
+		m.mutex.Unlock()
+	}
+}
+
+func (m *VirtualDeviceManager) untilNextRefresh() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var next time.Time
+	for _, device := range m.devices {
+		if !device.nextRefresh.IsZero() && (next.IsZero() || device.nextRefresh.Before(next)) {
+			next = device.nextRefresh
+		}
+	}
+	if next.IsZero() {
+		return time.Hour // nothing to refresh, a Register wakes us up
+	}
+	if wait := time.Until(next); wait > 0 {
+		return wait
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package fahapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// virtualDevicePuts returns the ttls of all PUTs of the virtual device with the native serial.
+func virtualDevicePuts(f *fakeSysAP, serial string) []string {
+	var ttls []string
+	for _, put := range f.recordedPuts() {
+		if !strings.HasSuffix(put.Path, "/api/rest/virtualdevice/"+testSysAP+"/"+serial) {
+			continue
+		}
+		var message VirtualDevice
+		json.Unmarshal([]byte(put.Body), &message)
+		ttls = append(ttls, message.Properties.Ttl)
+	}
+	return ttls
+}
+
+func TestVirtualDeviceManager(t *testing.T) {
+	f := newFakeSysAP(t, nil)
+	c := startClient(t, f)
+	m := c.NewVirtualDeviceManager()
+	ctx := testContext(t)
+
+	tests := []struct {
+		serial   string
+		ttl      time.Duration
+		wantTTL  string
+		refresh  bool
+		deviceId string
+	}{
+		{serial: "refreshed", ttl: 2 * time.Second, wantTTL: "2", refresh: true, deviceId: "6000REFRESHED"},
+		{serial: "forever", ttl: -1, wantTTL: "-1", deviceId: "6000FOREVER"},
+	}
+	for _, test := range tests {
+		deviceId, err := m.Register(ctx, testSysAP, test.serial, VirtualDeviceType_SwitchingActuator, test.serial, test.ttl)
+		if err != nil || deviceId != test.deviceId {
+			t.Fatalf("Register %s: got %s, %v", test.serial, deviceId, err)
+		}
+		if native, ok := m.NativeSerial(testSysAP, deviceId); !ok || native != test.serial {
+			t.Errorf("NativeSerial(%s) = %s", deviceId, native)
+		}
+	}
+
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	// refreshed after half of its TTL
+	waitFor(t, "refresh", func() bool { return len(virtualDevicePuts(f, "refreshed")) >= 2 })
+	for _, test := range tests {
+		ttls := virtualDevicePuts(f, test.serial)
+		if !test.refresh && len(ttls) != 1 {
+			t.Errorf("%s: %d PUTs, want no refresh", test.serial, len(ttls))
+		}
+		for _, ttl := range ttls {
+			if ttl != test.wantTTL {
+				t.Errorf("%s: PUT with ttl %s, want %s", test.serial, ttl, test.wantTTL)
+			}
+		}
+	}
+	for _, device := range m.Devices() {
+		if device.LastRefresh.IsZero() || device.DeviceId == "" {
+			t.Errorf("device %+v wasn't refreshed", device)
+		}
+	}
+
+	if err := m.Unregister(ctx, testSysAP, "forever", true); err != nil {
+		t.Fatal(err)
+	}
+	if ttls := virtualDevicePuts(f, "forever"); ttls[len(ttls)-1] != "0" {
+		t.Errorf("Unregister sent ttl %s, want 0", ttls[len(ttls)-1])
+	}
+	if _, ok := m.DeviceId(testSysAP, "forever"); ok {
+		t.Error("unregistered device is still managed")
+	}
+
+	if err := m.Shutdown(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %s", err)
+	}
+	if ttls := virtualDevicePuts(f, "refreshed"); ttls[len(ttls)-1] != "0" {
+		t.Errorf("Shutdown sent ttl %s, want 0", ttls[len(ttls)-1])
+	}
+	if len(m.Devices()) != 0 {
+		t.Errorf("%d devices managed after Shutdown", len(m.Devices()))
+	}
+}
+
+func TestVirtualDeviceManagerShutdownDropsCommands(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"6000OLD": testSwitch("Old", "0"),
+		"6000NEW": testSwitch("New", "0"),
+	})
+	c := startClient(t, f)
+	ctx := testContext(t)
+
+	commands := make(chan string, 10)
+	handlers := func(serial string) VirtualSwitchingActuatorHandlers {
+		return VirtualSwitchingActuatorHandlers{Switch: func(ctx context.Context, on bool) (bool, error) {
+			commands <- serial
+			return on, nil
+		}}
+	}
+	old := c.NewVirtualDeviceManager()
+	if _, err := old.NewVirtualSwitchingActuator(ctx, testSysAP, "old", "Old", -1, handlers("old")); err != nil {
+		t.Fatal(err)
+	}
+	current := c.NewVirtualDeviceManager()
+	if _, err := current.NewVirtualSwitchingActuator(ctx, testSysAP, "new", "New", -1, handlers("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Shutdown(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	c.virtualMutex.Lock()
+	managers := c.virtualManagers
+	c.virtualMutex.Unlock()
+	if len(managers) != 1 || managers[0] != current {
+		t.Errorf("client still knows %d managers, want only the running one", len(managers))
+	}
+
+	conn := startLoop(t, f, c)
+	sendDatapoints(t, conn, map[string]string{"6000OLD/ch0000/idp0000": "1"})
+	sendDatapoints(t, conn, map[string]string{"6000NEW/ch0000/idp0000": "1"})
+	if serial := <-commands; serial != "new" {
+		t.Errorf("command for %s, want new", serial)
+	}
+	select {
+	case serial := <-commands:
+		t.Errorf("command for %s after the shutdown", serial)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
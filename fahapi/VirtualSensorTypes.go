@@ -0,0 +1,204 @@
+package fahapi
+
+import (
+	"context"
+	"time"
+)
+
+// Virtual sensors publish values of external data sources (e.g. a weather station or window contacts, which are
+// not part of free@home). The SysAP sends no commands to them. Unchanged values are not published again.
+
+// newVirtualSensor registers the virtual sensor and returns its handle for the typed sensor.
+func (m *VirtualDeviceManager) newVirtualSensor(ctx context.Context, sysapId string, nativeSerial string, deviceType VirtualDeviceType, displayName string, ttl time.Duration) (*virtualDeviceHandle, error) {
+	v := m.newVirtualDeviceHandle(sysapId, nativeSerial)
+	if err := v.register(ctx, deviceType, displayName, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// VirtualWeatherStation is a virtual weather station with temperature, brightness, wind and rain channels.
+type VirtualWeatherStation struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualWeatherStation registers a virtual weather station (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualWeatherStation(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualWeatherStation, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_WeatherStation, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualWeatherStation{v}, nil
+}
+
+// SetTemperature publishes the outdoor temperature in °C (AL_OUTDOOR_TEMPERATURE).
+func (v *VirtualWeatherStation) SetTemperature(ctx context.Context, degree float64) error {
+	return v.publish(ctx, AL_OUTDOOR_TEMPERATURE, degree)
+}
+
+// SetBrightness publishes the brightness in lux (AL_BRIGHTNESS_LEVEL).
+func (v *VirtualWeatherStation) SetBrightness(ctx context.Context, lux float64) error {
+	return v.publish(ctx, AL_BRIGHTNESS_LEVEL, lux)
+}
+
+// SetWindSpeed publishes the wind speed in m/s (AL_WIND_SPEED).
+func (v *VirtualWeatherStation) SetWindSpeed(ctx context.Context, speed float64) error {
+	return v.publish(ctx, AL_WIND_SPEED, speed)
+}
+
+// SetRain publishes the rain alarm (AL_RAIN_ALARM).
+func (v *VirtualWeatherStation) SetRain(ctx context.Context, rain bool) error {
+	return v.publish(ctx, AL_RAIN_ALARM, rain)
+}
+
+// VirtualTemperatureSensor is a virtual outdoor temperature sensor.
+type VirtualTemperatureSensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualTemperatureSensor registers a virtual temperature sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualTemperatureSensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualTemperatureSensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_Weather_TemperatureSensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualTemperatureSensor{v}, nil
+}
+
+// SetTemperature publishes the outdoor temperature in °C (AL_OUTDOOR_TEMPERATURE).
+func (v *VirtualTemperatureSensor) SetTemperature(ctx context.Context, degree float64) error {
+	return v.publish(ctx, AL_OUTDOOR_TEMPERATURE, degree)
+}
+
+// VirtualBrightnessSensor is a virtual brightness sensor.
+type VirtualBrightnessSensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualBrightnessSensor registers a virtual brightness sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualBrightnessSensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualBrightnessSensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_Weather_BrightnessSensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualBrightnessSensor{v}, nil
+}
+
+// SetBrightness publishes the brightness in lux (AL_BRIGHTNESS_LEVEL).
+func (v *VirtualBrightnessSensor) SetBrightness(ctx context.Context, lux float64) error {
+	return v.publish(ctx, AL_BRIGHTNESS_LEVEL, lux)
+}
+
+// VirtualRainSensor is a virtual rain sensor.
+type VirtualRainSensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualRainSensor registers a virtual rain sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualRainSensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualRainSensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_Weather_RainSensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualRainSensor{v}, nil
+}
+
+// SetRain publishes the rain alarm (AL_RAIN_ALARM).
+func (v *VirtualRainSensor) SetRain(ctx context.Context, rain bool) error {
+	return v.publish(ctx, AL_RAIN_ALARM, rain)
+}
+
+// VirtualWindSensor is a virtual wind sensor.
+type VirtualWindSensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualWindSensor registers a virtual wind sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualWindSensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualWindSensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_Weather_WindSensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualWindSensor{v}, nil
+}
+
+// SetWindSpeed publishes the wind speed in m/s (AL_WIND_SPEED).
+func (v *VirtualWindSensor) SetWindSpeed(ctx context.Context, speed float64) error {
+	return v.publish(ctx, AL_WIND_SPEED, speed)
+}
+
+// VirtualWindowSensor is a virtual window or door contact.
+type VirtualWindowSensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualWindowSensor registers a virtual window sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualWindowSensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualWindowSensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_WindowSensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualWindowSensor{v}, nil
+}
+
+// SetOpen publishes whether the window or door is open (AL_WINDOW_DOOR).
+func (v *VirtualWindowSensor) SetOpen(ctx context.Context, open bool) error {
+	return v.publish(ctx, AL_WINDOW_DOOR, open)
+}
+
+// VirtualBinarySensor is a virtual binary sensor, e.g. a contact of another system.
+type VirtualBinarySensor struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualBinarySensor registers a virtual binary sensor (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualBinarySensor(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualBinarySensor, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_BinarySensor, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualBinarySensor{v}, nil
+}
+
+// SetOn publishes the state of the sensor (AL_SWITCH_ON_OFF).
+func (v *VirtualBinarySensor) SetOn(ctx context.Context, on bool) error {
+	return v.publish(ctx, AL_SWITCH_ON_OFF, on)
+}
+
+// VirtualCODetector is a virtual carbon monoxide detector.
+type VirtualCODetector struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualCODetector registers a virtual CO detector (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualCODetector(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualCODetector, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_CODetector, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualCODetector{v}, nil
+}
+
+// SetCOAlarm publishes the CO alarm (AL_CO_ALARM_ACTIVE).
+func (v *VirtualCODetector) SetCOAlarm(ctx context.Context, alarm bool) error {
+	return v.publish(ctx, AL_CO_ALARM_ACTIVE, alarm)
+}
+
+// VirtualFireDetector is a virtual smoke / fire detector.
+type VirtualFireDetector struct {
+	*virtualDeviceHandle
+}
+
+// NewVirtualFireDetector registers a virtual fire detector (see Register for the ttl).
+func (m *VirtualDeviceManager) NewVirtualFireDetector(ctx context.Context, sysapId string, nativeSerial string, displayName string, ttl time.Duration) (*VirtualFireDetector, error) {
+	v, err := m.newVirtualSensor(ctx, sysapId, nativeSerial, VirtualDeviceType_FireDetector, displayName, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualFireDetector{v}, nil
+}
+
+// SetFireAlarm publishes the fire alarm (AL_FIRE_ALARM_ACTIVE).
+func (v *VirtualFireDetector) SetFireAlarm(ctx context.Context, alarm bool) error {
+	return v.publish(ctx, AL_FIRE_ALARM_ACTIVE, alarm)
+}
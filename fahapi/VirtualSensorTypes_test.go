@@ -0,0 +1,59 @@
+package fahapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVirtualSensorDeduplication(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"6000OUTSIDE": testDevice("Outside", FID_TEMPERATURE_SENSOR, nil,
+			map[string]*InOutPut{"odp0000": datapoint(AL_OUTDOOR_TEMPERATURE, "0")}),
+	})
+	c := startClient(t, f)
+	ctx := testContext(t)
+	m := c.NewVirtualDeviceManager()
+
+	sensor, err := m.NewVirtualTemperatureSensor(ctx, testSysAP, "outside", "Outside", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	temperaturePuts := func() int {
+		count := 0
+		for _, put := range f.recordedPuts() {
+			if strings.HasSuffix(put.Path, "/6000OUTSIDE.ch0000.odp0000") {
+				count++
+			}
+		}
+		return count
+	}
+	setTemperature := func(degree float64, wantPuts int) {
+		t.Helper()
+		if err := sensor.SetTemperature(ctx, degree); err != nil {
+			t.Fatal(err)
+		}
+		if puts := temperaturePuts(); puts != wantPuts {
+			t.Errorf("SetTemperature(%v): %d PUTs, want %d", degree, puts, wantPuts)
+		}
+	}
+
+	setTemperature(12.5, 1)
+	setTemperature(12.5, 1) // unchanged
+	setTemperature(13, 2)
+
+	// registering again re-creates the device, the value is published again
+	if _, err := m.Register(ctx, testSysAP, "outside", VirtualDeviceType_Weather_TemperatureSensor, "Outside", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	setTemperature(13, 3)
+	setTemperature(13, 3)
+
+	// so does the refresh of Run
+	lastRefresh := m.Devices()[0].LastRefresh
+	go m.Run(ctx)
+	t.Cleanup(m.Stop)
+	waitFor(t, "refresh", func() bool { return m.Devices()[0].LastRefresh.After(lastRefresh) })
+	setTemperature(13, 4)
+	setTemperature(13, 4)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"strconv"
 )
 
 const luminanceLevel = 0.02 // change has to be bigger than that: logarithm change of lux
@@ -40,17 +39,25 @@ func (ws *WeatherStationBrightnessUnit) updateUnitFromOutDatapoint(outPut *InOut
 
 	// f 41
 	switch *outPut.PairingID {
-	case 0x0004: // AL_SCENE_CONTROL (Recall or learn the set value related to encoded scene number)
-	case 0x0402: // AL_BRIGHTNESS_ALARM
-		alarm := *outPut.Value != "0"
+	case AL_SCENE_CONTROL: // Recall or learn the set value related to encoded scene number
+	case AL_BRIGHTNESS_ALARM:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		alarm := value.Bool
 		if alarm != ws.LuminanceAlarm {
 			ws.LuminanceAlarm = alarm
 			ws.LuminanceAlarmSet = true
 			changed = true
 		}
-	case 0x0403: // AL_BRIGHTNESS_LEVEL
-		luminance, _ := strconv.ParseFloat(*outPut.Value, 64)
-		if math.Abs(math.Log(ws.Luminance)-math.Log(luminance)) >= luminanceLevel && luminance != 0.0 {
+	case AL_BRIGHTNESS_LEVEL:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		luminance := value.Float
+		if luminanceChanged(ws.Luminance, luminance) {
 			ws.Luminance = luminance
 			ws.LuminanceSet = true
 			changed = true
@@ -66,9 +73,15 @@ func (ws *WeatherStationBrightnessUnit) resetChanged() {
 	ws.LuminanceAlarmSet = false
 }
 
-func weatherStationBrightnessFactory(deviceId string, device *Device, channelId string) Unit {
+func (ws *WeatherStationBrightnessUnit) snapshot() Unit {
+	snapshot := *ws
+	snapshot.UnitData = ws.snapshotData()
+	return &snapshot
+}
+
+func weatherStationBrightnessFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	ws := WeatherStationBrightnessUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeWeatherStationBrightness),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeWeatherStationBrightness),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
@@ -77,3 +90,12 @@ func weatherStationBrightnessFactory(deviceId string, device *Device, channelId
 
 	return &ws
 }
+
+// luminanceChanged reports whether the brightness changed noticeably (see luminanceLevel). A change from or to
+// 0 lux always counts.
+func luminanceChanged(old, new float64) bool {
+	if old == 0 || new == 0 {
+		return old != new
+	}
+	return math.Abs(math.Log(old)-math.Log(new)) >= luminanceLevel
+}
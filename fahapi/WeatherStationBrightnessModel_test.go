@@ -0,0 +1,35 @@
+package fahapi
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestWeatherStationBrightnessLuminance(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testDevice("Brightness", FID_BRIGHTNESS_SENSOR, nil, map[string]*InOutPut{
+			"odp0000": datapoint(AL_BRIGHTNESS_LEVEL, "0"),
+		}),
+		"ABB700000002": testSwitch("Light", "0"),
+	})
+	c := startClient(t, f)
+	key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+	if ws := CastWSB(c.LookupUnit(key)); ws.Luminance != 0 {
+		t.Errorf("hydrated luminance %f, want 0", ws.Luminance)
+	}
+
+	conn := startLoop(t, f, c)
+	for _, value := range []float64{120, 0, 0.5} {
+		raw := strconv.FormatFloat(value, 'f', -1, 64)
+		sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": raw})
+		waitFor(t, "luminance "+raw, func() bool { return CastWSB(c.LookupUnit(key)).Luminance == value })
+	}
+
+	// changes below luminanceLevel are ignored, the switch shows that the update has been processed
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "0.501"})
+	sendDatapoints(t, conn, map[string]string{"ABB700000002/ch0000/odp0000": "1"})
+	waitFor(t, "light on", func() bool { return CastSAU(c.LookupUnit(getUnitMapKey(testSysAP, "ABB700000002", "ch0000"))).On })
+	if ws := CastWSB(c.LookupUnit(key)); ws.Luminance != 0.5 {
+		t.Errorf("luminance %f, want 0.5", ws.Luminance)
+	}
+}
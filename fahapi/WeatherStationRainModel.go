@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"strconv"
 )
 
 const rainLevel = 2 // rain percentage change has to be bigger than that
@@ -40,22 +39,30 @@ func (ws *WeatherStationRainUnit) updateUnitFromOutDatapoint(outPut *InOutPut) b
 
 	// f 42
 	switch *outPut.PairingID {
-	case 0x0004: // AL_SCENE_CONTROL (Recall or learn the set value related to encoded scene number)
-	case 0x0027: // AL_RAIN_ALARM
-		alarm := *outPut.Value != "0"
+	case AL_SCENE_CONTROL: // Recall or learn the set value related to encoded scene number
+	case AL_RAIN_ALARM:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		alarm := value.Bool
 		if alarm != ws.RainAlarm {
 			ws.RainAlarm = alarm
 			ws.RainAlarmSet = true
 			changed = true
 		}
-	case 0x0405: // AL_RAIN_SENSOR_ACTIVATION_PERCENTAGE
-		rainPercentage, _ := strconv.Atoi(*outPut.Value)
+	case AL_RAIN_SENSOR_ACTIVATION_PERCENTAGE:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		rainPercentage := value.Int
 		if math.Abs(float64(ws.RainPercentage-rainPercentage)) > rainLevel {
 			ws.RainPercentage = rainPercentage
 			ws.RainPercentageSet = true
 			changed = true
 		}
-	case 0x0406: // AL_RAIN_SENSOR_FREQUENCY
+	case AL_RAIN_SENSOR_FREQUENCY:
 	}
 
 	return changed
@@ -66,9 +73,15 @@ func (ws *WeatherStationRainUnit) resetChanged() {
 	ws.RainPercentageSet = false
 }
 
-func weatherStationRainFactory(deviceId string, device *Device, channelId string) Unit {
+func (ws *WeatherStationRainUnit) snapshot() Unit {
+	snapshot := *ws
+	snapshot.UnitData = ws.snapshotData()
+	return &snapshot
+}
+
+func weatherStationRainFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	ws := WeatherStationRainUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeWeatherStationRain),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeWeatherStationRain),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"strconv"
 )
 
 // change has to be bigger than that
@@ -42,16 +41,24 @@ func (ws *WeatherStationTemperatureUnit) updateUnitFromOutDatapoint(outPut *InOu
 
 	// f 43
 	switch *outPut.PairingID {
-	case 0x0004: // AL_SCENE_CONTROL (Recall or learn the set value related to encoded scene number)
-	case 0x0026: // AL_FROST_ALARM
-		freezeAlarm := *outPut.Value != "0"
+	case AL_SCENE_CONTROL: // Recall or learn the set value related to encoded scene number
+	case AL_FROST_ALARM:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		freezeAlarm := value.Bool
 		if freezeAlarm != ws.FreezeAlarm {
 			ws.FreezeAlarm = freezeAlarm
 			ws.FreezeAlarmSet = true
 			changed = true
 		}
-	case 0x0400: // AL_OUTDOOR_TEMPERATURE
-		temperature, _ := strconv.ParseFloat(*outPut.Value, 64)
+	case AL_OUTDOOR_TEMPERATURE:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		temperature := value.Float
 		if math.Abs(ws.Temperature-temperature) >= temperatureLevel {
 			if temperature == 0.0 && math.Abs(ws.Temperature) > 5.0 {
 				log.Printf("Unplausible temp change: from %.2f°C to 0°C. Ignored.", ws.Temperature)
@@ -71,9 +78,15 @@ func (ws *WeatherStationTemperatureUnit) resetChanged() {
 	ws.FreezeAlarmSet = false
 }
 
-func weatherStationTemperatureFactory(deviceId string, device *Device, channelId string) Unit {
+func (ws *WeatherStationTemperatureUnit) snapshot() Unit {
+	snapshot := *ws
+	snapshot.UnitData = ws.snapshotData()
+	return &snapshot
+}
+
+func weatherStationTemperatureFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	ws := WeatherStationTemperatureUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeWeatherStationTemperature),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeWeatherStationTemperature),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
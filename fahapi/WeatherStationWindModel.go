@@ -3,7 +3,6 @@ package fahapi
 import (
 	"fmt"
 	"log"
-	"strconv"
 )
 
 type WeatherStationWindUnit struct {
@@ -39,23 +38,35 @@ func (ws *WeatherStationWindUnit) updateUnitFromOutDatapoint(outPut *InOutPut) b
 
 	// f 44
 	switch *outPut.PairingID {
-	case 0x0004: // AL_SCENE_CONTROL (Recall or learn the set value related to encoded scene number)
-	case 0x0025: // AL_WIND_ALARM
-		alarm := *outPut.Value != "0"
+	case AL_SCENE_CONTROL: // Recall or learn the set value related to encoded scene number
+	case AL_WIND_ALARM:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		alarm := value.Bool
 		if alarm != ws.WindAlarm {
 			ws.WindAlarm = alarm
 			ws.WindAlarmSet = true
 			changed = true
 		}
-	case 0x0401: // AL_WIND_FORCE
-		windForce, _ := strconv.ParseFloat(*outPut.Value, 64)
+	case AL_WIND_FORCE:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		windForce := value.Float
 		if windForce != ws.WindForce {
 			ws.WindForce = windForce
 			ws.WindForceSet = true
 			changed = true
 		}
-	case 0x0404: // AL_WIND_SPEED
-		wind, _ := strconv.ParseFloat(*outPut.Value, 64)
+	case AL_WIND_SPEED:
+		value, ok := ws.decode(outPut)
+		if !ok {
+			break
+		}
+		wind := value.Float
 		if wind != ws.Wind {
 			ws.Wind = wind
 			ws.WindSet = true
@@ -72,9 +83,15 @@ func (ws *WeatherStationWindUnit) resetChanged() {
 	ws.WindForceSet = false
 }
 
-func weatherStationWindFactory(deviceId string, device *Device, channelId string) Unit {
+func (ws *WeatherStationWindUnit) snapshot() Unit {
+	snapshot := *ws
+	snapshot.UnitData = ws.snapshotData()
+	return &snapshot
+}
+
+func weatherStationWindFactory(c *Client, sysapId string, deviceId string, device *Device, channelId string) Unit {
 	ws := WeatherStationWindUnit{
-		UnitData: unitDataFactory(deviceId, channelId, UntTypeWeatherStationWind),
+		UnitData: c.unitDataFactory(sysapId, deviceId, channelId, UntTypeWeatherStationWind),
 	}
 
 	for _, inOut := range device.Channels[channelId].Outputs {
@@ -1,54 +1,151 @@
 package fahapi
 
 import (
+	"context"
 	json2 "encoding/json"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 )
 
-func StartWebSocketLoop(refreshTime int) error {
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt, syscall.SIGHUP)
+// bounds of the exponential backoff between two reconnect attempts
+const reconnectMinBackoff = time.Second
+const reconnectMaxBackoff = 2 * time.Minute
 
-	u := url.URL{Scheme: "ws", Host: apiConfig.Host, Path: WebSocketPath}
-	if logLevel > 0 {
-		logger.Printf("connecting to %s", u.String())
+// StartWebSocketLoop connects to the websocket of the SysAP and processes all updates.
+// If the connection is lost (e.g. the SysAP reboots), it reconnects with exponential backoff and
+// resyncs the devices and units with the current configuration of the SysAP. Units which changed
+// while we were disconnected are reported via the normal update callback.
+//
+// The loop runs until ctx is cancelled or Stop is called; then it closes the connection cleanly and returns nil.
+// Only an error of the very first connection attempt is returned.
+// The loop doesn't handle any OS signals - that's up to the application (see Stop and FlushAllUnits).
+func (c *Client) StartWebSocketLoop(ctx context.Context, refreshTime int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.loopMutex.Lock()
+	if c.stopLoop != nil {
+		c.loopMutex.Unlock()
+		return fmt.Errorf("websocket loop is already running")
 	}
+	c.stopLoop = cancel
+	c.loopMutex.Unlock()
 
-	header := http.Header{}
-	header.Set("Authorization", apiConfig.Authentication)
-	c, _, err := websocket.DefaultDialer.Dial(u.String(), header)
-	if err != nil {
-		return err
+	defer func() {
+		c.loopMutex.Lock()
+		c.stopLoop = nil
+		c.loopMutex.Unlock()
+	}()
+
+	backoff := reconnectMinBackoff
+	connectedBefore := false
+
+	for {
+		conn, err := c.dialWebSocket(ctx)
+		if err != nil && !connectedBefore {
+			return err
+		}
+
+		if err == nil {
+			c.publish(&ConnectionStateChangedEvent{eventHead: newEventHead(), State: ConnectionConnected})
+			backoff = reconnectMinBackoff
+			if connectedBefore {
+				c.resyncAfterReconnect(ctx)
+			}
+			connectedBefore = true
+
+			var stopped bool
+			stopped, err = c.runWebSocket(ctx, conn, refreshTime)
+			conn.Close()
+			if stopped {
+				c.publish(&ConnectionStateChangedEvent{eventHead: newEventHead(), State: ConnectionStopped, Err: err})
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			c.publish(&ConnectionStateChangedEvent{eventHead: newEventHead(), State: ConnectionStopped})
+			return nil
+		}
+		c.logger.Printf("websocket connection lost (%v), reconnecting in %s\n", err, backoff)
+		c.publish(&ConnectionStateChangedEvent{eventHead: newEventHead(), State: ConnectionDisconnected, Err: err})
+		if c.waitForReconnect(ctx, backoff) {
+			c.publish(&ConnectionStateChangedEvent{eventHead: newEventHead(), State: ConnectionStopped})
+			return nil
+		}
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
 	}
-	defer c.Close()
+}
+
+// Stop ends a running StartWebSocketLoop.
+func (c *Client) Stop() {
+	c.loopMutex.Lock()
+	defer c.loopMutex.Unlock()
+	if c.stopLoop != nil {
+		c.stopLoop()
+	}
+}
+
+// FlushAllUnits reports all units as updated via the update callback and logs them.
+// While the websocket loop is running, the flush is done by the loop, otherwise immediately.
+func (c *Client) FlushAllUnits() {
+	c.loopMutex.Lock()
+	running := c.stopLoop != nil
+	c.loopMutex.Unlock()
+
+	if !running {
+		c.treatAllUnitsAsUpdated(true)
+		return
+	}
+	select {
+	case c.flushRequest <- struct{}{}:
+	default: // a flush is already pending
+	}
+}
 
+func (c *Client) dialWebSocket(ctx context.Context) (*websocket.Conn, error) {
+	u := url.URL{Scheme: c.webSocketScheme(), Host: c.host, Path: WebSocketPath}
+	if c.logLevel > 0 {
+		c.logger.Printf("connecting to %s", u.String())
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", c.authentication)
+	conn, _, err := c.wsDialer.DialContext(ctx, u.String(), header)
+	return conn, err
+}
+
+// runWebSocket processes the messages of one websocket connection until it breaks or ctx is done.
+// stopped is true, if the loop should not reconnect.
+func (c *Client) runWebSocket(ctx context.Context, conn *websocket.Conn, refreshTime int) (stopped bool, err error) {
 	done := make(chan struct{})
+	var readErr error
 
 	go func() {
 		defer close(done)
 		for {
-			_, message, err := c.ReadMessage()
+			_, message, err := conn.ReadMessage()
 			if err != nil {
-				logger.Printf("read:", err)
+				c.logger.Println("read:", err)
+				readErr = err
 				return
 			}
-			if logLevel == 3 { // debug out
+			if c.logLevel == 3 { // debug out
 				fmt.Printf("%s\n", message)
 			}
 			var result WebsocketMessage
 			err = json2.Unmarshal(message, &result)
 			if err != nil {
-				logger.Printf("WS unmarshall error: %s\n", err)
+				c.reportError(fmt.Errorf("WS unmarshall error: %w", err))
 			} else {
-				processWebsocketMessage(result)
+				c.processWebsocketMessage(ctx, result)
 			}
 		}
 	}()
@@ -61,60 +158,159 @@ func StartWebSocketLoop(refreshTime int) error {
 	for {
 		select {
 		case <-done:
-			return nil
+			return false, readErr
 		case t := <-ticker.C:
-			err := c.WriteMessage(websocket.TextMessage, []byte(t.String()))
+			err := conn.WriteMessage(websocket.TextMessage, []byte(t.String()))
 			if err != nil {
-				logger.Println("ticker write:", err)
-				return err
+				c.logger.Println("ticker write:", err)
+				conn.Close() // let the reader goroutine end
+				<-done
+				return false, err
 			}
 			ticks++
 			if ticks > refreshTime {
 				ticks = 0
 				// todo Maybe we should also refresh the whole UnitMap structure (re read the f@h configuration)
-				treatAllUnitsAsUpdated(false) // regulary flush all units
+				c.treatAllUnitsAsUpdated(false) // regulary flush all units
 			}
-		case sig := <-interrupt:
-			logger.Println("interrupt", sig)
-
-			if sig.String() == "hangup" {
-				treatAllUnitsAsUpdated(true)
-			} else {
-				// Cleanly close the connection by sending a close message and then
-				// waiting (with timeout) for the server to close the connection.
-				err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-				if err != nil {
-					logger.Println("write close:", err)
-					return err
-				}
-				select {
-				case <-done:
-				case <-time.After(time.Second):
-				}
-				return nil
+		case <-c.flushRequest:
+			c.treatAllUnitsAsUpdated(true)
+		case <-ctx.Done():
+			if c.logLevel > 0 {
+				c.logger.Println("stopping websocket loop")
 			}
+			// Cleanly close the connection by sending a close message and then
+			// waiting (with timeout) for the server to close the connection.
+			err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			if err != nil {
+				c.logger.Println("write close:", err)
+				return true, err
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+			}
+			return true, nil
 		}
 	}
 }
 
-func processWebsocketMessage(message WebsocketMessage) {
-	if wsUpdateMessageCallback != nil {
-		wsUpdateMessageCallback(message) // tell someone about the new message
+// waitForReconnect waits for the backoff duration. It returns true, if ctx is done meanwhile.
+func (c *Client) waitForReconnect(ctx context.Context, backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return false
+		case <-c.flushRequest:
+			c.treatAllUnitsAsUpdated(true)
+		case <-ctx.Done():
+			return true
+		}
 	}
+}
 
-	changedKeys := updateDevices(message)
+// resyncAfterReconnect reconciles our state with the SysAP and reports the units changed meanwhile.
+func (c *Client) resyncAfterReconnect(ctx context.Context) {
+	changedKeys, err := c.resync(ctx)
+	if err != nil {
+		c.reportError(err)
+		return
+	}
+	if c.logLevel > 0 {
+		c.logger.Printf("resynced after reconnect: %d units changed\n", len(changedKeys))
+	}
 	if len(changedKeys) > 0 {
-		handleUpdatedUnits(changedKeys, logLevel > 0)
+		c.handleUpdatedUnits(changedKeys, c.logLevel > 0)
 	}
+	c.publishQueuedEvents() // e.g. removed devices
 }
 
-func updateDevices(message WebsocketMessage) []string {
+func (c *Client) processWebsocketMessage(ctx context.Context, message WebsocketMessage) {
+	if c.wsUpdateMessageCallback != nil {
+		c.wsUpdateMessageCallback(message) // tell someone about the new message
+	}
+
+	changedKeys := c.updateDevices(ctx, message)
+	if len(changedKeys) > 0 {
+		c.handleUpdatedUnits(changedKeys, c.logLevel > 0)
+	}
+	c.handleVirtualCommands(ctx)
+	c.publishQueuedEvents() // e.g. removed devices
+
+	for sysapId, sysapMessage := range message {
+		if sysapMessage == nil {
+			continue
+		}
+		for sceneId, payload := range sysapMessage.ScenesTriggered {
+			c.publish(c.sceneTriggeredEvent(sysapId, sceneId, payload))
+		}
+	}
+}
+
+func (c *Client) updateDevices(ctx context.Context, message WebsocketMessage) []string {
 	changedMap := make(map[string]bool)
+	unknownDevices := make(map[string]map[string]bool) // SysAP UUID -> device serials
+	var errs []error
+
+	c.stateMutex.Lock()
+	for sysapId, sysapMessage := range message {
+		if sysapMessage == nil {
+			continue
+		}
+		unknownDevices[sysapId], errs = c.updateSysAPDevices(sysapId, sysapMessage, changedMap, errs)
+	}
+	c.stateMutex.Unlock()
+
+	for _, err := range errs {
+		c.reportError(err)
+	}
+
+	// load the unknown devices without holding the lock
+	for sysapId, deviceIds := range unknownDevices {
+		for deviceId := range deviceIds {
+			if _, err := c.addNewDevice(ctx, sysapId, deviceId); err != nil {
+				c.reportError(fmt.Errorf("[updateDevices] No device %s found on SysAP %s and failed to load it: %w", deviceId, sysapId, err))
+			}
+		}
+	}
+
+	// unique list of all changed sysap/device.channel combinations
+	changedKeys := make([]string, 0, len(changedMap))
+	for k := range changedMap {
+		changedKeys = append(changedKeys, k)
+	}
+
+	return changedKeys
+}
+
+// updateSysAPDevices applies the datapoints and removed devices of the message. It returns the serials
+// of unknown and added devices, which have to be loaded, and appends all errors to errs.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) updateSysAPDevices(sysapId string, message *WebsocketSysAPMessage, changedMap map[string]bool, errs []error) (map[string]bool, []error) {
+	unknownDevices := make(map[string]bool)
+
+	for _, deviceId := range message.DevicesAdded {
+		if _, ok := c.freeDevices[sysapId][deviceId]; !ok {
+			unknownDevices[deviceId] = true
+		}
+	}
+
+	for deviceId, payload := range message.Devices {
+		if payload == nil {
+			continue
+		}
+		c.applyDevicePayload(sysapId, deviceId, payload, changedMap)
+		delete(unknownDevices, deviceId)
+	}
 
-	for updDatapoint, updValue := range message.ZeroSysAp.Datapoints {
+	for updDatapoint, updValue := range message.Datapoints {
 		split := strings.Split(updDatapoint, "/")
 		if len(split) != 3 {
-			logger.Fatalf("illegal message %x: illegal datapoint format %s", message, updDatapoint)
+			errs = append(errs, fmt.Errorf("illegal datapoint format %s (value %s) from SysAP %s", updDatapoint, updValue, sysapId))
+			continue
 		}
 		deviceId := split[0]
 		channelId := split[1]
@@ -125,22 +321,27 @@ func updateDevices(message WebsocketMessage) []string {
 		var outPoint *InOutPut
 		var ok bool
 
-		if device, ok = FreeDevices[deviceId]; !ok {
-			var err error
-			if device, err = addNewDevice(deviceId); err != nil {
-				logger.Printf("error: [updateDevices] No device %s found and failed to load it: %s\n", deviceId, err)
-			}
+		if device, ok = c.freeDevices[sysapId][deviceId]; !ok {
+			unknownDevices[deviceId] = true
 			continue
 		}
 		if channel, ok = device.Channels[channelId]; !ok {
-			if logLevel > 1 {
-				logger.Printf("warning: [updateDevices] No channel %s for device %s\n", channelId, deviceId)
+			if c.logLevel > 1 {
+				c.logger.Printf("warning: [updateDevices] No channel %s for device %s\n", channelId, deviceId)
+			}
+			continue
+		}
+		if inPoint, isInput := channel.Inputs[outDatapointId]; isInput && inPoint != nil {
+			updateDeviceDatapoint(inPoint, updValue)
+			if key, changed := c.reHydrateUnitInput(sysapId, deviceId, channelId, inPoint); changed {
+				changedMap[key] = true
 			}
+			c.queueVirtualCommand(sysapId, deviceId, channelId, inPoint)
 			continue
 		}
 		if outPoint, ok = channel.Outputs[outDatapointId]; !ok {
-			if logLevel > 1 {
-				logger.Printf("warning: [updateDevices] No out datapoint %s for device %s and channel %s\n", outDatapointId, deviceId, channelId)
+			if c.logLevel > 1 {
+				c.logger.Printf("warning: [updateDevices] No out datapoint %s for device %s and channel %s\n", outDatapointId, deviceId, channelId)
 			}
 			continue
 		}
@@ -149,20 +350,180 @@ func updateDevices(message WebsocketMessage) []string {
 		updateDeviceDatapoint(outPoint, updValue)
 
 		// 2) update the corresponding unit data structures
-		key, changed := reHydrateUnitValue(deviceId, channelId, outPoint)
+		key, changed := c.reHydrateUnitValue(sysapId, deviceId, channelId, outPoint)
+		c.matchPendingWrites(sysapId, deviceId, channelId, outPoint)
 
 		if changed {
 			changedMap[key] = true
+			if unit, ok := c.unitMap[key].(transitionUnit); ok {
+				unit.updateUnitFromTransition(outPoint)
+			}
 		}
 	}
 
-	// unique list of all changed device.channel combinations
-	changedKeys := make([]string, 0, len(changedMap))
-	for k := range changedMap {
-		changedKeys = append(changedKeys, k)
+	for _, deviceId := range message.DevicesRemoved {
+		delete(unknownDevices, deviceId)
+		device, ok := c.freeDevices[sysapId][deviceId]
+		if !ok {
+			continue
+		}
+		if c.logLevel > 0 {
+			c.logger.Printf("Remove device %s on SysAP %s\n", deviceId, sysapId)
+		}
+		c.removeDevice(sysapId, deviceId, device)
+		delete(c.freeDevices[sysapId], deviceId)
+		for channelId := range device.Channels {
+			delete(changedMap, getUnitMapKey(sysapId, deviceId, channelId))
+		}
 	}
 
-	return changedKeys
+	return unknownDevices, errs
+}
+
+// reportError logs the error and passes it to the error callback.
+func (c *Client) reportError(err error) {
+	c.logger.Printf("error: %s\n", err)
+	if c.errorCallback != nil {
+		c.errorCallback(err)
+	}
+	c.publish(&ErrorEvent{eventHead: newEventHead(), Err: err})
+}
+
+// applyDevicePayload merges a complete device object sent via websocket into our device.
+// If the metadata changed (name, floor, room, function ID, channels, ...), the units of the device are
+// hydrated again. The caller has to hold the write lock of stateMutex.
+func (c *Client) applyDevicePayload(sysapId string, deviceId string, payload *Device, changedMap map[string]bool) {
+	oldDevice, ok := c.freeDevices[sysapId][deviceId]
+	if !ok {
+		if _, ok := c.freeDevices[sysapId]; !ok {
+			c.freeDevices[sysapId] = make(map[string]*Device)
+		}
+		device := compactDevice(cloneDevice(payload))
+		c.freeDevices[sysapId][deviceId] = device
+		newUnitKeys := c.hydrateDevice(sysapId, deviceId, device)
+		c.queueEvent(&DeviceAddedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, Device: cloneDevice(device)})
+		if c.logLevel > 0 {
+			c.logger.Printf("Add new device %s on SysAP %s from websocket (resulting in %d new Units)\n", deviceId, sysapId, len(newUnitKeys))
+		}
+		return
+	}
+
+	merged := mergeDevice(oldDevice, payload)
+	if !deviceStructureChanged(oldDevice, merged) {
+		for _, key := range c.resyncDeviceValues(sysapId, deviceId, oldDevice, merged) {
+			changedMap[key] = true
+		}
+		return
+	}
+
+	if c.logLevel > 0 {
+		c.logger.Printf("Metadata of device %s on SysAP %s changed\n", deviceId, sysapId)
+	}
+	withEvents := c.hasSubscribers()
+	for channelId := range oldDevice.Channels {
+		key := getUnitMapKey(sysapId, deviceId, channelId)
+		if unit, ok := c.unitMap[key]; ok {
+			if withEvents {
+				c.rememberUnitBeforeChange(key, unit.snapshot())
+			}
+			delete(c.unitMap, key)
+		}
+	}
+	c.freeDevices[sysapId][deviceId] = merged
+	for _, key := range c.hydrateDevice(sysapId, deviceId, merged) {
+		changedMap[key] = true
+		if withEvents {
+			c.rememberUnitBeforeChange(key, nil)
+		}
+	}
+	for key := range c.unitsBeforeChange {
+		if _, ok := c.unitMap[key]; !ok {
+			delete(c.unitsBeforeChange, key)
+		}
+	}
+	c.queueEvent(&DeviceMetadataChangedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, Old: cloneDevice(oldDevice), New: cloneDevice(merged)})
+}
+
+// mergeDevice returns a copy of device with all fields set in payload applied. Channels and datapoints
+// sent as null are skipped.
+func mergeDevice(device *Device, payload *Device) *Device {
+	merged := cloneDevice(device)
+	if payload.DisplayName != nil {
+		merged.DisplayName = payload.DisplayName
+	}
+	if payload.Floor != nil {
+		merged.Floor = payload.Floor
+	}
+	if payload.Room != nil {
+		merged.Room = payload.Room
+	}
+	if payload.Interface != nil {
+		merged.Interface = payload.Interface
+	}
+	if payload.NativeId != nil {
+		merged.NativeId = payload.NativeId
+	}
+	if payload.Unresponsive != nil {
+		merged.Unresponsive = payload.Unresponsive
+	}
+	if merged.Channels == nil {
+		merged.Channels = make(map[string]*Channel, len(payload.Channels))
+	}
+	for channelId, channelPayload := range payload.Channels {
+		if channelPayload == nil {
+			continue
+		}
+		channel, ok := merged.Channels[channelId]
+		if !ok {
+			channel = cloneChannel(channelPayload)
+			compactInOutPuts(channel.Inputs)
+			compactInOutPuts(channel.Outputs)
+			merged.Channels[channelId] = channel
+			continue
+		}
+		if channelPayload.DisplayName != nil {
+			channel.DisplayName = channelPayload.DisplayName
+		}
+		if channelPayload.Type != nil {
+			channel.Type = channelPayload.Type
+		}
+		if channelPayload.FunctionID != nil {
+			channel.FunctionID = channelPayload.FunctionID
+		}
+		if channelPayload.Floor != nil {
+			channel.Floor = channelPayload.Floor
+		}
+		if channelPayload.Room != nil {
+			channel.Room = channelPayload.Room
+		}
+		channel.Inputs = mergeInOutPuts(channel.Inputs, channelPayload.Inputs)
+		channel.Outputs = mergeInOutPuts(channel.Outputs, channelPayload.Outputs)
+	}
+	return merged
+}
+
+func mergeInOutPuts(inOutPuts map[string]*InOutPut, payload map[string]*InOutPut) map[string]*InOutPut {
+	if inOutPuts == nil && len(payload) > 0 {
+		inOutPuts = make(map[string]*InOutPut, len(payload))
+	}
+	for id, inOutPayload := range payload {
+		if inOutPayload == nil {
+			continue
+		}
+		inOut, ok := inOutPuts[id]
+		if !ok || inOut == nil {
+			copied := *inOutPayload
+			inOutPuts[id] = &copied
+			continue
+		}
+		if inOutPayload.PairingID != nil {
+			inOut.PairingID = inOutPayload.PairingID
+		}
+		if inOutPayload.Value != nil {
+			inOut.Value = inOutPayload.Value
+		}
+	}
+	return inOutPuts
 }
 
 func updateDeviceDatapoint(data *InOutPut, updValue string) {
@@ -170,22 +531,31 @@ func updateDeviceDatapoint(data *InOutPut, updValue string) {
 }
 
 // new device is added to the system - add it to our Device and our Unit list
-func addNewDevice(deviceId string) (device *Device, err error) {
-	if device, err = GetDevice("00000000-0000-0000-0000-000000000000", deviceId); err != nil {
+func (c *Client) addNewDevice(ctx context.Context, sysapId string, deviceId string) (device *Device, err error) {
+	if device, err = c.GetDevice(ctx, sysapId, deviceId); err != nil {
 		return
 	}
+	compactDevice(device)
 
-	FreeDevices[deviceId] = device
-	newUnitKeys := hydrateDevice(deviceId, device)
+	c.stateMutex.Lock()
+	defer c.publishQueuedEvents()
+	defer c.stateMutex.Unlock()
+
+	if _, ok := c.freeDevices[sysapId]; !ok {
+		c.freeDevices[sysapId] = make(map[string]*Device)
+	}
+	c.freeDevices[sysapId][deviceId] = device
+	newUnitKeys := c.hydrateDevice(sysapId, deviceId, device)
+	c.queueEvent(&DeviceAddedEvent{eventHead: newEventHead(), SysApId: sysapId, DeviceId: deviceId, Device: cloneDevice(device)})
 
-	if logLevel > 0 {
+	if c.logLevel > 0 {
 		virtual := ""
 		if device.NativeId != nil {
 			virtual = fmt.Sprintf("virtual [%s] ", *device.NativeId)
 		}
-		logger.Printf("Add new %sdevice %s (resulting in %d new Units)\n", virtual, deviceId, len(newUnitKeys))
+		c.logger.Printf("Add new %sdevice %s on SysAP %s (resulting in %d new Units)\n", virtual, deviceId, sysapId, len(newUnitKeys))
 		for _, key := range newUnitKeys {
-			logger.Println(UnitMap[key].String())
+			c.logger.Println(c.unitMap[key].String())
 		}
 	}
 
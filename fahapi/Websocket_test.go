@@ -0,0 +1,299 @@
+package fahapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectResync(t *testing.T) {
+	devices := map[string]*Device{
+		"ABB700000001": testSwitch("Light", "0"),
+		"ABB700000002": testSwitch("Removed", "0"),
+	}
+	f := newFakeSysAP(t, devices)
+	c := startClient(t, f)
+	sub := c.Subscribe(100, EventTypeFilter(EventConnectionStateChanged, EventUnitChanged, EventDeviceAdded, EventDeviceRemoved))
+	defer sub.Close()
+	conn := startLoop(t, f, c)
+	if event := nextEvent(t, sub).(*ConnectionStateChangedEvent); event.State != ConnectionConnected {
+		t.Fatalf("got %s, want connected", event.State)
+	}
+
+	// while we are disconnected, the light is switched on, a device is added and one is removed
+	f.setDevices(map[string]*Device{
+		"ABB700000001": testSwitch("Light", "1"),
+		"ABB700000003": testSwitch("Added", "1"),
+	})
+	conn.Close()
+
+	if event := nextEvent(t, sub).(*ConnectionStateChangedEvent); event.State != ConnectionDisconnected {
+		t.Fatalf("got %s, want disconnected", event.State)
+	}
+	conn = f.nextConn()
+	if event := nextEvent(t, sub).(*ConnectionStateChangedEvent); event.State != ConnectionConnected {
+		t.Fatalf("got %s, want connected", event.State)
+	}
+
+	changed := make(map[string]*UnitChangedEvent)
+	var added, removed []string
+	for len(changed) < 2 || len(added) < 1 || len(removed) < 1 {
+		switch event := nextEvent(t, sub).(type) {
+		case *UnitChangedEvent:
+			changed[event.Key] = event
+		case *DeviceAddedEvent:
+			added = append(added, event.DeviceId)
+		case *DeviceRemovedEvent:
+			removed = append(removed, event.DeviceId)
+		default:
+			t.Fatalf("unexpected event %T", event)
+		}
+	}
+
+	light := changed[getUnitMapKey(testSysAP, "ABB700000001", "ch0000")]
+	if light == nil || CastSAU(light.Old).On || !CastSAU(light.New).On {
+		t.Errorf("switching on the light while disconnected wasn't reported: %+v", light)
+	}
+	if event := changed[getUnitMapKey(testSysAP, "ABB700000003", "ch0000")]; event == nil || event.Old != nil {
+		t.Errorf("unit of the added device wasn't reported as new: %+v", event)
+	}
+	if added[0] != "ABB700000003" || removed[0] != "ABB700000002" {
+		t.Errorf("added %v, removed %v", added, removed)
+	}
+	if c.LookupUnit(getUnitMapKey(testSysAP, "ABB700000002", "ch0000")) != nil {
+		t.Error("unit of the removed device still exists")
+	}
+
+	// the new connection is used for the updates
+	sendDatapoints(t, conn, map[string]string{"ABB700000003/ch0000/odp0000": "0"})
+	waitFor(t, "update via the new connection", func() bool {
+		return !CastSAU(c.LookupUnit(getUnitMapKey(testSysAP, "ABB700000003", "ch0000"))).On
+	})
+}
+
+func TestWebsocketDeviceChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     WebsocketSysAPMessage
+		wantEvent   EventType
+		wantDevice  string
+		wantUnit    bool // unit of wantDevice exists after the message
+		wantDisplay string
+	}{
+		{
+			name:       "devicesAdded loads the device",
+			message:    WebsocketSysAPMessage{DevicesAdded: []string{"ABB700000002"}},
+			wantEvent:  EventDeviceAdded,
+			wantDevice: "ABB700000002",
+			wantUnit:   true,
+		},
+		{
+			name:       "unknown datapoint loads the device",
+			message:    WebsocketSysAPMessage{Datapoints: map[string]string{"ABB700000002/ch0000/odp0000": "1"}},
+			wantEvent:  EventDeviceAdded,
+			wantDevice: "ABB700000002",
+			wantUnit:   true,
+		},
+		{
+			name:       "devicesRemoved drops the device",
+			message:    WebsocketSysAPMessage{DevicesRemoved: []string{"ABB700000001"}},
+			wantEvent:  EventDeviceRemoved,
+			wantDevice: "ABB700000001",
+		},
+		{
+			name: "device payload merges the metadata",
+			message: WebsocketSysAPMessage{Devices: map[string]*Device{
+				"ABB700000001": {Channels: map[string]*Channel{"ch0000": {DisplayName: strPtr("Renamed")}}},
+			}},
+			wantEvent:   EventDeviceMetadataChanged,
+			wantDevice:  "ABB700000001",
+			wantUnit:    true,
+			wantDisplay: "Renamed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+			c := startClient(t, f)
+			conn := startLoop(t, f, c)
+			f.setDevices(map[string]*Device{
+				"ABB700000001": testSwitch("Light", "0"),
+				"ABB700000002": testSwitch("New", "1"),
+			})
+			sub := c.Subscribe(10, EventTypeFilter(test.wantEvent))
+			defer sub.Close()
+
+			message := test.message
+			if err := conn.WriteJSON(WebsocketMessage{testSysAP: &message}); err != nil {
+				t.Fatal(err)
+			}
+			event := nextEvent(t, sub)
+
+			var deviceId string
+			switch event := event.(type) {
+			case *DeviceAddedEvent:
+				deviceId = event.DeviceId
+			case *DeviceRemovedEvent:
+				deviceId = event.DeviceId
+			case *DeviceMetadataChangedEvent:
+				deviceId = event.DeviceId
+			}
+			if deviceId != test.wantDevice {
+				t.Errorf("%s for device %s, want %s", event.Type(), deviceId, test.wantDevice)
+			}
+			unit := c.LookupUnit(getUnitMapKey(testSysAP, test.wantDevice, "ch0000"))
+			if (unit != nil) != test.wantUnit {
+				t.Errorf("unit exists: %v, want %v", unit != nil, test.wantUnit)
+			}
+			if test.wantDisplay != "" {
+				if name := *unit.GetChannel().DisplayName; name != test.wantDisplay {
+					t.Errorf("channel name %s, want %s", name, test.wantDisplay)
+				}
+			}
+		})
+	}
+}
+
+func TestWebsocketNullPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices string // devices part of the message
+	}{
+		{"null channel", `{"ABB700000001":{"channels":{"ch0000":null}}}`},
+		{"null datapoints", `{"ABB700000001":{"channels":{"ch0000":{"inputs":{"idp0000":null},"outputs":{"odp0000":null}}}}}`},
+		{"new channel with null datapoints", `{"ABB700000001":{"channels":{"ch0001":{"functionID":"7","outputs":{"odp0000":null}}}}}`},
+		{"new device with null channel", `{"ABB700000002":{"displayName":"New","channels":{"ch0000":null,"ch0001":{"outputs":{"odp0000":null}}}}}`},
+		{"null device", `{"ABB700000002":null}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+			c := startClient(t, f)
+			conn := startLoop(t, f, c)
+
+			message := `{"` + testSysAP + `":{"devices":` + test.devices + `}}`
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+				t.Fatal(err)
+			}
+			// the reader goroutine is still alive and the device keeps working
+			sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "1"})
+			waitFor(t, "update after the partial payload", func() bool {
+				unit := c.LookupUnit(getUnitMapKey(testSysAP, "ABB700000001", "ch0000"))
+				return unit != nil && CastSAU(unit).On
+			})
+		})
+	}
+}
+
+func TestWebsocketLoopStops(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(c *Client, cancel context.CancelFunc)
+	}{
+		{"Stop", func(c *Client, cancel context.CancelFunc) { c.Stop() }},
+		{"context", func(c *Client, cancel context.CancelFunc) { cancel() }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+			c := startClient(t, f)
+			sub := c.Subscribe(10, EventTypeFilter(EventConnectionStateChanged))
+			defer sub.Close()
+
+			ctx, cancel := context.WithCancel(testContext(t))
+			done := make(chan error)
+			go func() { done <- c.StartWebSocketLoop(ctx, 1000) }()
+			conn := f.nextConn()
+			if event := nextEvent(t, sub).(*ConnectionStateChangedEvent); event.State != ConnectionConnected {
+				t.Fatalf("got %s, want connected", event.State)
+			}
+			if err := c.StartWebSocketLoop(ctx, 1000); err == nil {
+				t.Error("second loop started")
+			}
+
+			test.stop(c, cancel)
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("loop returned %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("loop didn't stop")
+			}
+			if event := nextEvent(t, sub).(*ConnectionStateChangedEvent); event.State != ConnectionStopped {
+				t.Errorf("got %s, want stopped", event.State)
+			}
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+						t.Errorf("connection ended with %v, want a normal close", err)
+					}
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWebsocketLoopFirstConnectionFails(t *testing.T) {
+	f := newFakeSysAP(t, nil)
+	c := startClient(t, f)
+	f.server.Close()
+
+	done := make(chan error)
+	go func() { done <- c.StartWebSocketLoop(testContext(t), 1000) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("loop returned without error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loop retries the first connection")
+	}
+}
+
+func TestWebsocketErrorsAreReported(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{
+		"ABB700000001": testSwitch("Light", "0"),
+		"ABB700000002": testDevice("RTC", FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITHOUT_FAN, nil,
+			map[string]*InOutPut{"odp0000": datapoint(AL_INFO_ERROR, "3")}),
+	})
+	c := startClient(t, f)
+	errs := make(chan error, 10)
+	c.SetErrorCallback(func(err error) { errs <- err })
+
+	// a device error is a fault state of the unit
+	if rtc := CastRTC(c.LookupChannelUnit(testSysAP, "ABB700000002", "ch0000")); !rtc.HasFault() || rtc.ErrorCode != 3 {
+		t.Errorf("error code %d, want a fault", rtc.ErrorCode)
+	}
+
+	conn := startLoop(t, f, c)
+	for _, message := range []string{
+		`not json`,
+		`{"` + testSysAP + `":{"datapoints":{"ABB700000001/odp0000":"1"}}}`,
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Errorf("%s: nil error reported", message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: no error reported", message)
+		}
+	}
+
+	// the loop goes on
+	sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": "1"})
+	waitFor(t, "update after the errors", func() bool {
+		return CastSAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000")).On
+	})
+}
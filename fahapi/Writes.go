@@ -0,0 +1,283 @@
+package fahapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultWriteTimeout is used by Write, if WriteOptions.Timeout is not set.
+const DefaultWriteTimeout = 10 * time.Second
+
+// WriteOptions configure a tracked write (see UnitData.Write).
+type WriteOptions struct {
+	// Timeout after which the write is given up, if the device didn't confirm it.
+	Timeout time.Duration
+	// Optimistic applies the value to the unit immediately (reported like any other change).
+	// It is rolled back to the value reported by the device, if the write fails.
+	Optimistic bool
+}
+
+type WriteState string
+
+const (
+	WritePending      WriteState = "pending"
+	WriteConfirmed    WriteState = "confirmed"
+	WriteContradicted WriteState = "contradicted" // the device reported another value
+	WriteTimedOut     WriteState = "timed out"
+	WriteFailed       WriteState = "failed" // the SysAP rejected the write
+)
+
+// writeConfirmation tells which output confirms a write to an input.
+type writeConfirmation struct {
+	output      int
+	tolerance   float64
+	contradicts bool // false for values passing intermediate values, like the position of a moving blind or a ramping dimmer
+}
+
+var writeConfirmations = map[int]writeConfirmation{
+	AL_SWITCH_ON_OFF:                           {AL_INFO_ON_OFF, 0, true},
+	AL_ABSOLUTE_SET_VALUE_CONTROL:              {AL_INFO_ACTUAL_DIMMING_VALUE, 1, false},
+	AL_SET_ABSOLUTE_POSITION_BLINDS_PERCENTAGE: {AL_CURRENT_ABSOLUTE_POSITION_BLINDS_PERCENTAGE, 1, false},
+	AL_SET_ABSOLUTE_POSITION_SLATS_PERCENTAGE:  {AL_CURRENT_ABSOLUTE_POSITION_SLATS_PERCENTAGE, 1, false},
+	AL_ABSOLUTE_SET_POINT_REQUEST:              {AL_SET_POINT_TEMPERATURE, 0.05, true},
+	AL_ECO_ON_OFF:                              {AL_ECO_ON_OFF, 0, true},
+	AL_CONTROLLER_ON_OFF_REQUEST:               {AL_CONTROLLER_ON_OFF, 0, true},
+}
+
+// WriteHandle tracks a write until the device confirms it via its AL_INFO_* output, reports another value,
+// or the timeout expires.
+type WriteHandle struct {
+	UnitKey         string
+	PairingId       int    // input written
+	OutputPairingId int    // output confirming the write
+	Value           string // encoded value
+
+	client       *Client
+	sysapId      string
+	deviceId     string
+	channelId    string
+	expected     DatapointValue
+	confirmation writeConfirmation
+	optimistic   bool
+	timer        *time.Timer
+	done         chan struct{}
+
+	mutex  sync.Mutex
+	state  WriteState
+	actual string // value reported by the device
+	err    error
+}
+
+// Done is closed when the write is no longer pending.
+func (h *WriteHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// State returns the current state of the write.
+func (h *WriteHandle) State() WriteState {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.state
+}
+
+// Err returns nil, if the write is pending or confirmed. Otherwise it returns an error wrapping
+// ErrWriteContradicted, ErrWriteTimeout or the error of PutDatapoint.
+func (h *WriteHandle) Err() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.err
+}
+
+// Actual returns the value reported by the device, which confirmed or contradicted the write.
+func (h *WriteHandle) Actual() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.actual
+}
+
+// Wait waits until the write is resolved or ctx is done and returns the error of the write (see Err).
+func (h *WriteHandle) Wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Write writes the value to the input of the unit with the given pairing id, like the setters of the units,
+// and tracks its confirmation. Only inputs with a known confirming output can be tracked
+// (e.g. AL_SWITCH_ON_OFF is confirmed by AL_INFO_ON_OFF).
+func (u *UnitData) Write(ctx context.Context, pairingId int, value interface{}, options WriteOptions) (*WriteHandle, error) {
+	c := u.client
+	if c == nil {
+		return nil, fmt.Errorf("unit %s is not connected to a client", u.getUnitMapKey())
+	}
+	confirmation, ok := writeConfirmations[pairingId]
+	if !ok {
+		return nil, fmt.Errorf("no confirmation known for writes to %s: %w", PairingIdName(pairingId), ErrNotFound)
+	}
+	raw, err := EncodeValue(pairingId, value)
+	if err != nil {
+		return nil, err
+	}
+	expected, _ := DecodeValue(confirmation.output, raw)
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWriteTimeout
+	}
+
+	h := &WriteHandle{
+		UnitKey:         u.getUnitMapKey(),
+		PairingId:       pairingId,
+		OutputPairingId: confirmation.output,
+		Value:           raw,
+		client:          c,
+		sysapId:         u.SysApId,
+		deviceId:        u.SerialNumber,
+		channelId:       u.ChannelId,
+		expected:        expected,
+		confirmation:    confirmation,
+		optimistic:      options.Optimistic,
+		done:            make(chan struct{}),
+		state:           WritePending,
+	}
+
+	// start the timer before the handle is published, so that resolve always sees it; the timer
+	// can't give up the write before it is pending, as giveUpWrite needs stateMutex
+	c.stateMutex.Lock()
+	h.mutex.Lock()
+	h.timer = time.AfterFunc(timeout, func() {
+		c.giveUpWrite(h, WriteTimedOut, fmt.Errorf("%s of %s not confirmed within %s: %w", PairingIdName(pairingId), h.UnitKey, timeout, ErrWriteTimeout))
+	})
+	h.mutex.Unlock()
+	c.pendingWrites = append(c.pendingWrites, h)
+	if h.optimistic {
+		outputPairing := confirmation.output
+		c.reHydrateUnitValue(h.sysapId, h.deviceId, h.channelId, &InOutPut{PairingID: &outputPairing, Value: &raw})
+	}
+	c.stateMutex.Unlock()
+	if h.optimistic {
+		c.handleUpdatedUnits([]string{h.UnitKey}, false)
+	}
+
+	if err := u.putInput(ctx, pairingId, raw); err != nil {
+		c.giveUpWrite(h, WriteFailed, err)
+		return h, err
+	}
+
+	// the device doesn't report a value, which didn't change
+	c.stateMutex.Lock()
+	if output := c.deviceOutput(h.sysapId, h.deviceId, h.channelId, confirmation.output); output != nil && output.Value != nil {
+		if actual, err := DecodeValue(confirmation.output, *output.Value); err == nil && h.matches(actual) {
+			c.resolveWrite(h, WriteConfirmed, *output.Value, nil)
+		}
+	}
+	c.stateMutex.Unlock()
+
+	return h, nil
+}
+
+func (h *WriteHandle) matches(actual DatapointValue) bool {
+	return math.Abs(actual.Float-h.expected.Float) <= h.confirmation.tolerance
+}
+
+// resolve ends a pending write. It returns false, if the write was already resolved.
+func (h *WriteHandle) resolve(state WriteState, actual string, err error) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.state != WritePending {
+		return false
+	}
+	h.state = state
+	h.actual = actual
+	h.err = err
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	close(h.done)
+	return true
+}
+
+// resolveWrite resolves the write and drops it from the pending writes.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) resolveWrite(h *WriteHandle, state WriteState, actual string, err error) bool {
+	if !h.resolve(state, actual, err) {
+		return false
+	}
+	for i, pending := range c.pendingWrites {
+		if pending == h {
+			c.pendingWrites = append(c.pendingWrites[:i], c.pendingWrites[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// giveUpWrite resolves a failed or timed out write and rolls back an optimistic value.
+// The caller must not hold stateMutex.
+func (c *Client) giveUpWrite(h *WriteHandle, state WriteState, err error) {
+	c.stateMutex.Lock()
+	if !c.resolveWrite(h, state, "", err) {
+		c.stateMutex.Unlock()
+		return
+	}
+	rolledBack := false
+	if h.optimistic {
+		if output := c.deviceOutput(h.sysapId, h.deviceId, h.channelId, h.OutputPairingId); output != nil {
+			_, rolledBack = c.reHydrateUnitValue(h.sysapId, h.deviceId, h.channelId, output)
+		}
+	}
+	c.stateMutex.Unlock()
+
+	if rolledBack {
+		c.handleUpdatedUnits([]string{h.UnitKey}, false)
+	}
+}
+
+// matchPendingWrites resolves the pending writes confirmed or contradicted by an updated output datapoint.
+// The unit has already been updated with the reported value, so a contradicted optimistic value is rolled back.
+// The caller has to hold the write lock of stateMutex.
+func (c *Client) matchPendingWrites(sysapId string, deviceId string, channelId string, outPut *InOutPut) {
+	if len(c.pendingWrites) == 0 || outPut.PairingID == nil || outPut.Value == nil {
+		return
+	}
+	key := getUnitMapKey(sysapId, deviceId, channelId)
+	for _, h := range append([]*WriteHandle(nil), c.pendingWrites...) {
+		if h.UnitKey != key || h.OutputPairingId != *outPut.PairingID {
+			continue
+		}
+		actual, err := DecodeValue(*outPut.PairingID, *outPut.Value)
+		if err != nil {
+			continue
+		}
+		if h.matches(actual) {
+			c.resolveWrite(h, WriteConfirmed, *outPut.Value, nil)
+		} else if h.confirmation.contradicts {
+			c.resolveWrite(h, WriteContradicted, *outPut.Value,
+				fmt.Errorf("%s reported %s instead of %s: %w", key, *outPut.Value, h.Value, ErrWriteContradicted))
+		}
+	}
+}
+
+// deviceOutput returns the output datapoint of the channel with the given pairing id.
+// The caller has to hold the read lock of stateMutex.
+func (c *Client) deviceOutput(sysapId string, deviceId string, channelId string, pairingId int) *InOutPut {
+	device, ok := c.freeDevices[sysapId][deviceId]
+	if !ok {
+		return nil
+	}
+	channel, ok := device.Channels[channelId]
+	if !ok || channel == nil {
+		return nil
+	}
+	for _, output := range channel.Outputs {
+		if output != nil && output.PairingID != nil && *output.PairingID == pairingId {
+			return output
+		}
+	}
+	return nil
+}
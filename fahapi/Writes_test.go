@@ -0,0 +1,146 @@
+package fahapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackedWrites(t *testing.T) {
+	tests := []struct {
+		name       string
+		on         bool
+		optimistic bool
+		putResult  string
+		reported   string // value reported by the device after the write, "" for none
+		wantState  WriteState
+		wantErr    error
+		wantOn     bool // state of the unit after the write is resolved
+	}{
+		{name: "confirmed", on: true, reported: "1", wantState: WriteConfirmed, wantOn: true},
+		{name: "confirmed at once", on: false, wantState: WriteConfirmed},
+		{name: "contradicted", on: true, reported: "0", wantState: WriteContradicted, wantErr: ErrWriteContradicted},
+		{name: "timed out", on: true, wantState: WriteTimedOut, wantErr: ErrWriteTimeout},
+		{name: "optimistic confirmed", on: true, optimistic: true, reported: "1", wantState: WriteConfirmed, wantOn: true},
+		{name: "optimistic timed out", on: true, optimistic: true, wantState: WriteTimedOut, wantErr: ErrWriteTimeout},
+		{name: "optimistic contradicted", on: true, optimistic: true, reported: "0", wantState: WriteContradicted, wantErr: ErrWriteContradicted},
+		{name: "rejected", on: true, optimistic: true, putResult: "ERROR", wantState: WriteFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+			if test.putResult != "" {
+				f.setPutResult(test.putResult, 0)
+			}
+			c := startClient(t, f)
+			conn := startLoop(t, f, c)
+			key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+			ctx := testContext(t)
+			handle, err := c.LookupUnit(key).GetUnitData().Write(ctx, AL_SWITCH_ON_OFF, test.on, WriteOptions{Timeout: 200 * time.Millisecond, Optimistic: test.optimistic})
+			if test.wantState == WriteFailed {
+				if err == nil || handle.State() != WriteFailed {
+					t.Fatalf("got error %v and state %s, want a failed write", err, handle.State())
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if test.optimistic && test.wantState != WriteFailed && !CastSAU(c.LookupUnit(key)).On {
+				t.Error("optimistic value wasn't applied at once")
+			}
+			if test.reported != "" {
+				sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": test.reported})
+			}
+
+			err = handle.Wait(ctx)
+			if handle.State() != test.wantState {
+				t.Errorf("state %s, want %s", handle.State(), test.wantState)
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) || test.wantErr == nil && test.wantState != WriteFailed && err != nil {
+				t.Errorf("error %v, want %v", err, test.wantErr)
+			}
+			if on := CastSAU(c.LookupUnit(key)).On; on != test.wantOn {
+				t.Errorf("unit is on: %v, want %v", on, test.wantOn)
+			}
+			c.stateMutex.RLock()
+			pending := len(c.pendingWrites)
+			c.stateMutex.RUnlock()
+			if pending != 0 {
+				t.Errorf("%d writes still pending", pending)
+			}
+		})
+	}
+}
+
+func TestConcurrentTrackedWrites(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+	c := startClient(t, f)
+	conn := startLoop(t, f, c)
+	unit := c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000").GetUnitData()
+	ctx := testContext(t)
+
+	// very short timeouts race with the confirmations and the registration of the writes
+	handles := make(chan *WriteHandle, 40)
+	for i := 0; i < cap(handles); i++ {
+		go func(i int) {
+			handle, err := unit.Write(ctx, AL_SWITCH_ON_OFF, i%2 == 0, WriteOptions{Timeout: time.Duration(i%4+1) * time.Millisecond, Optimistic: i%3 == 0})
+			if err != nil {
+				t.Error(err)
+			}
+			handles <- handle
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": []string{"0", "1"}[i%2]})
+	}
+	for i := 0; i < cap(handles); i++ {
+		handle := <-handles
+		if handle == nil {
+			continue
+		}
+		handle.Wait(ctx)
+		if handle.State() == WritePending {
+			t.Errorf("write of %s still pending", handle.Value)
+		}
+	}
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	if len(c.pendingWrites) != 0 {
+		t.Errorf("%d writes still pending", len(c.pendingWrites))
+	}
+}
+
+func TestTrackedDimmerWrites(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		reported  []string // values reported by the dimmer while ramping
+		wantState WriteState
+	}{
+		{"confirmed after the ramp", []string{"20", "50", "80"}, WriteConfirmed},
+		{"timed out within the ramp", []string{"20", "50"}, WriteTimedOut},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSysAP(t, map[string]*Device{
+				"ABB700000001": testDevice("Dimmer", FID_DIMMING_ACTUATOR,
+					map[string]*InOutPut{"idp0000": datapoint(AL_ABSOLUTE_SET_VALUE_CONTROL, "0")},
+					map[string]*InOutPut{"odp0000": datapoint(AL_INFO_ACTUAL_DIMMING_VALUE, "0")}),
+			})
+			c := startClient(t, f)
+			conn := startLoop(t, f, c)
+			key := getUnitMapKey(testSysAP, "ABB700000001", "ch0000")
+
+			ctx := testContext(t)
+			handle, err := c.LookupUnit(key).GetUnitData().Write(ctx, AL_ABSOLUTE_SET_VALUE_CONTROL, 80, WriteOptions{Timeout: 500 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range test.reported {
+				sendDatapoints(t, conn, map[string]string{"ABB700000001/ch0000/odp0000": value})
+			}
+			if err := handle.Wait(ctx); handle.State() != test.wantState {
+				t.Errorf("state %s (error %v), want %s", handle.State(), err, test.wantState)
+			}
+		})
+	}
+}
@@ -2,46 +2,31 @@ package fahapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	json2 "encoding/json"
 	"fmt"
+	"github.com/gorilla/websocket"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 )
 
-// see https://developer.eu.mybuildings.abb.com/fah_local/reference/functionids/
-type FunctionIdType string
+// All responses are keyed by the UUID of the SysAP they belong to.
 
-const (
-	FID_SWITCH_SENSOR                                  FunctionIdType = "0"
-	FID_DIMMING_SENSOR                                 FunctionIdType = "1"
-	FID_SWITCH_ACTUATOR                                FunctionIdType = "7"
-	FID_DIMMING_ACTUATOR                               FunctionIdType = "12"
-	FID_WINDOW_DOOR_SENSOR                             FunctionIdType = "f"
-	FID_ROOM_TEMPERATURE_CONTROLLER_MASTER_WITHOUT_FAN FunctionIdType = "23"
-	FID_BRIGHTNESS_SENSOR                              FunctionIdType = "41"
-	FID_RAIN_SENSOR                                    FunctionIdType = "42"
-	FID_TEMPERATURE_SENSOR                             FunctionIdType = "43"
-	FID_WIND_SENSOR                                    FunctionIdType = "44"
-)
-
-type ApiRestConfigurationGet200ApplicationJsonResponse struct {
-	ZeroSysAp *SysAP `json:"00000000-0000-0000-0000-000000000000"`
-}
+type ApiRestConfigurationGet200ApplicationJsonResponse map[string]*SysAP
 
-type ApiRestDatapointSysapSerialGet200ApplicationJsonResponse struct {
-	ZeroSysAp struct {
-		Values []string `json:"values,omitempty"`
-	} `json:"00000000-0000-0000-0000-000000000000"`
+type ApiRestDatapointSysapSerialGet200ApplicationJsonResponse map[string]struct {
+	Values []string `json:"values,omitempty"`
 }
 
 type ApiRestDatapointSysapSerialPut200TextPlainResponse map[string]struct{ Result string }
 
-type ApiRestDeviceSysapDeviceGet200ApplicationJsonResponse struct {
-	ZeroSysAp *Devices `json:"00000000-0000-0000-0000-000000000000"`
-}
+type ApiRestDeviceSysapDeviceGet200ApplicationJsonResponse map[string]*Devices
 
 // Channel defines model for Channel.
 type Channel struct {
@@ -64,9 +49,7 @@ type Device struct {
 	Channels     map[string]*Channel `json:"channels,omitempty"`
 }
 
-type Devicelist struct {
-	AdditionalProperties []string `json:"00000000-0000-0000-0000-000000000000"`
-}
+type Devicelist map[string][]string
 
 type Devices struct {
 	Devices map[string]*Device
@@ -117,14 +100,15 @@ type SysAP struct {
 	Users     *Users  `json:"users,omitempty"`
 }
 
-type WebsocketMessage struct {
-	ZeroSysAp struct {
-		Datapoints      map[string]string      `json:"datapoints"`
-		Devices         map[string]*Devices    `json:"devices"`
-		DevicesAdded    []string               `json:"devicesAdded"`
-		DevicesRemoved  []string               `json:"devicesRemoved"`
-		ScenesTriggered map[string]interface{} `json:"scenesTriggered"`
-	} `json:"00000000-0000-0000-0000-000000000000"`
+type WebsocketMessage map[string]*WebsocketSysAPMessage
+
+// WebsocketSysAPMessage is the part of a websocket message concerning one SysAP.
+type WebsocketSysAPMessage struct {
+	Datapoints      map[string]string           `json:"datapoints"`
+	Devices         map[string]*Device          `json:"devices"`
+	DevicesAdded    []string                    `json:"devicesAdded"`
+	DevicesRemoved  []string                    `json:"devicesRemoved"`
+	ScenesTriggered map[string]json2.RawMessage `json:"scenesTriggered"`
 }
 
 type VirtualDeviceProperties struct {
@@ -168,12 +152,10 @@ type VirtualDevicesSuccessXXX struct {
 	} `json:"-"`
 }
 */
-type VirtualDevicesSuccess struct {
-	ZeroSysAp struct {
-		Devices map[string]struct {
-			Serial string `json:"serial,omitempty"`
-		} `json:"devices,omitempty"`
-	} `json:"00000000-0000-0000-0000-000000000000"`
+type VirtualDevicesSuccess map[string]struct {
+	Devices map[string]struct {
+		Serial string `json:"serial,omitempty"`
+	} `json:"devices,omitempty"`
 }
 
 // ===============================================================================================
@@ -181,67 +163,139 @@ type VirtualDevicesSuccess struct {
 const ApiPathPrefix string = "/fhapi/v1"
 const WebSocketPath string = "/fhapi/v1/api/ws"
 
-type WebsocketUpdateUnitCallbackFunc func(unitKeys []string)
-type WebsocketUpdateMessageCallbackFunc func(message WebsocketMessage)
-
-var wsUpdateUnitCallback WebsocketUpdateUnitCallbackFunc
-var wsUpdateMessageCallback WebsocketUpdateMessageCallbackFunc
+// DefaultRequestTimeout limits every REST call of the default http.Client,
+// additionally to the deadline of the context passed to the call.
+const DefaultRequestTimeout = 30 * time.Second
 
-var FreeDevices map[string]*Device
-var SysAPConfiguration *SysAP
-var logger *log.Logger
-var logLevel int
+// WebsocketUpdateUnitCallbackFunc gets snapshots of all updated units. The snapshots are consistent
+// copies, which are not changed by later updates.
+type WebsocketUpdateUnitCallbackFunc func(units []Unit)
+type WebsocketUpdateMessageCallbackFunc func(message WebsocketMessage)
 
-type apiConfiguration struct {
-	Host           string
-	Authentication string
+// ErrorCallbackFunc gets errors which occur while processing websocket messages.
+// The loop itself keeps running.
+type ErrorCallbackFunc func(err error)
+
+// Client is the connection to one System Access Point. It holds the configuration
+// (host, credentials, logger, callbacks) and the device and unit state read from the SysAP.
+// Several independent clients can be used in one process.
+type Client struct {
+	host           string
+	scheme         string // http or https, see UseTLS
+	authentication string
+	httpClient     *http.Client
+	wsDialer       *websocket.Dialer
+	logger         *log.Logger
+	logLevel       int
+
+	wsUpdateUnitCallback    WebsocketUpdateUnitCallbackFunc
+	wsUpdateMessageCallback WebsocketUpdateMessageCallbackFunc
+	errorCallback           ErrorCallbackFunc
+
+	// device and unit state, guarded by stateMutex. Use the accessors (LookupUnit, LookupDevice, ...) to read it.
+	stateMutex         sync.RWMutex
+	freeDevices        map[string]map[string]*Device // SysAP UUID -> device serial -> device
+	sysAPConfiguration map[string]*SysAP             // SysAP UUID -> configuration
+	unitMap            map[string]Unit
+	unitsBeforeChange  map[string]Unit // snapshots of changed units for the UnitChangedEvent
+	queuedEvents       []Event
+	queuedErrors       []error // e.g. invalid datapoint values found while holding stateMutex
+	pendingWrites      []*WriteHandle
+	queuedCommands     []virtualCommand // inputs of virtual devices written by the SysAP, handled after unlocking
+
+	// managers of our virtual devices, see NewVirtualDeviceManager
+	virtualMutex    sync.Mutex
+	virtualManagers []*VirtualDeviceManager
+
+	// subscribers of the event stream, guarded by their own mutex, so publishing never waits for stateMutex
+	subscriberMutex sync.Mutex
+	subscribers     []*Subscription
+
+	countTickRounds int
+
+	loopMutex    sync.Mutex
+	stopLoop     context.CancelFunc // cancels the running websocket loop
+	flushRequest chan struct{}
 }
 
-var apiConfig = apiConfiguration{}
-
-func ConfigureApi(
+// NewClient creates a client for the SysAP at host. If logger is nil, the standard logger is used.
+func NewClient(
 	host string,
 	username string,
 	password string,
 	callbackUnit WebsocketUpdateUnitCallbackFunc,
 	callbackMessage WebsocketUpdateMessageCallbackFunc,
-	loggerParam *log.Logger,
-	logLevelParam int,
-) {
-	apiConfig.Host = host
-	apiConfig.Authentication = "Basic: " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
-	wsUpdateUnitCallback = callbackUnit
-	wsUpdateMessageCallback = callbackMessage
-	logger = loggerParam
-	logLevel = logLevelParam
+	logger *log.Logger,
+	logLevel int,
+) *Client {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	return &Client{
+		host:                    host,
+		scheme:                  "http",
+		authentication:          "Basic: " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
+		httpClient:              &http.Client{Timeout: DefaultRequestTimeout},
+		wsDialer:                websocket.DefaultDialer,
+		logger:                  logger,
+		logLevel:                logLevel,
+		wsUpdateUnitCallback:    callbackUnit,
+		wsUpdateMessageCallback: callbackMessage,
+		freeDevices:             make(map[string]map[string]*Device),
+		sysAPConfiguration:      make(map[string]*SysAP),
+		unitMap:                 make(map[string]Unit),
+		unitsBeforeChange:       make(map[string]Unit),
+		flushRequest:            make(chan struct{}, 1),
+	}
+}
+
+// SetHTTPClient replaces the http.Client used for all REST calls, e.g. to inject a custom Transport.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
 }
 
-func ReadAndHydradteAllDevices() {
-	configResult, err := GetConfiguration()
+// SetErrorCallback sets the callback for errors while processing websocket messages.
+func (c *Client) SetErrorCallback(callback ErrorCallbackFunc) {
+	c.errorCallback = callback
+}
+
+func (c *Client) ReadAndHydradteAllDevices(ctx context.Context) error {
+	configResult, err := c.GetConfiguration(ctx)
 	if err != nil {
-		logger.Fatalf("can't initialize f@h api: %s", err)
+		return fmt.Errorf("can't initialize f@h api: %w", err)
 	}
 
-	SysAPConfiguration = configResult
-	FreeDevices = configResult.Devices
+	c.stateMutex.Lock()
+	c.sysAPConfiguration = configResult
+	c.freeDevices = make(map[string]map[string]*Device, len(configResult))
+	for sysapId, sysap := range configResult {
+		if sysap.Devices == nil {
+			sysap.Devices = make(map[string]*Device)
+		}
+		compactDevices(sysap.Devices)
+		c.freeDevices[sysapId] = sysap.Devices
+	}
+	c.hydrateAllDevices(c.freeDevices)
+	c.stateMutex.Unlock()
 
-	hydrateAllDevices(FreeDevices)
+	c.treatAllUnitsAsUpdated(false) // initially handle all units as updated - e.g. send all to influx
+	return nil
 }
 
-func GetDeviceList() (*Devicelist, error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s", apiConfig.Host, ApiPathPrefix, "/api/rest/devicelist")
-	json, err := loadUrl(httpUrl)
+func (c *Client) GetDeviceList(ctx context.Context) (Devicelist, error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/devicelist")
+	json, err := c.loadUrl(ctx, httpUrl)
 	if err != nil {
 		return nil, err
 	}
 	var result Devicelist
 	err = json2.Unmarshal(json, &result)
-	return &result, err
+	return result, err
 }
 
-func GetDevice(sysap string, deviceId string) (*Device, error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s/%s/%s", apiConfig.Host, ApiPathPrefix, "/api/rest/device", sysap, deviceId)
-	json, err := loadUrl(httpUrl)
+func (c *Client) GetDevice(ctx context.Context, sysap string, deviceId string) (*Device, error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s/%s/%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/device", sysap, deviceId)
+	json, err := c.loadUrl(ctx, httpUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -250,25 +304,33 @@ func GetDevice(sysap string, deviceId string) (*Device, error) {
 	if err != nil {
 		return nil, err
 	}
-	device := result.ZeroSysAp.Devices[deviceId]
-	return device, err
+	devices, ok := result[sysap]
+	if !ok || devices == nil || devices.Devices[deviceId] == nil {
+		return nil, fmt.Errorf("GET device %s returned no device for SysAP %s: %w", deviceId, sysap, ErrNotFound)
+	}
+	return devices.Devices[deviceId], nil
 }
 
-func GetDatapoint(sysap string, deviceId string, channelId string, datapointId string) (string, error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s/%s/%s.%s.%s", apiConfig.Host, ApiPathPrefix, "/api/rest/datapoint", sysap, deviceId, channelId, datapointId)
-	json, err := loadUrl(httpUrl)
+func (c *Client) GetDatapoint(ctx context.Context, sysap string, deviceId string, channelId string, datapointId string) (string, error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s/%s/%s.%s.%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/datapoint", sysap, deviceId, channelId, datapointId)
+	json, err := c.loadUrl(ctx, httpUrl)
 	if err != nil {
 		return "", err
 	}
 	var result ApiRestDatapointSysapSerialGet200ApplicationJsonResponse
-	err = json2.Unmarshal(json, &result)
-	point := result.ZeroSysAp.Values[0]
-	return point, err
+	if err = json2.Unmarshal(json, &result); err != nil {
+		return "", err
+	}
+	if values := result[sysap].Values; len(values) > 0 {
+		return values[0], nil
+	}
+	return "", fmt.Errorf("GET datapoint %s.%s.%s returned no value for SysAP %s: %w", deviceId, channelId, datapointId, sysap, ErrNotFound)
 }
 
-func GetConfiguration() (*SysAP, error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s", apiConfig.Host, ApiPathPrefix, "/api/rest/configuration")
-	json, err := loadUrl(httpUrl)
+// GetConfiguration returns the configuration of all SysAPs, keyed by SysAP UUID.
+func (c *Client) GetConfiguration(ctx context.Context) (map[string]*SysAP, error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/configuration")
+	json, err := c.loadUrl(ctx, httpUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -279,17 +341,17 @@ func GetConfiguration() (*SysAP, error) {
 		return nil, err
 	}
 
-	return result.ZeroSysAp, err
+	return result, err
 }
 
-func PutDatapoint(sysap string, deviceId string, channelId string, datapointId string, value string) (bool, error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s/%s/%s.%s.%s", apiConfig.Host, ApiPathPrefix, "/api/rest/datapoint", sysap, deviceId, channelId, datapointId)
+func (c *Client) PutDatapoint(ctx context.Context, sysap string, deviceId string, channelId string, datapointId string, value string) (bool, error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s/%s/%s.%s.%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/datapoint", sysap, deviceId, channelId, datapointId)
 
 	var err error
 	var bstr, body []byte
 	bstr = []byte(value)
 
-	if body, err = putRequest(httpUrl, bytes.NewBuffer(bstr)); err != nil {
+	if body, err = c.putRequest(ctx, httpUrl, bytes.NewBuffer(bstr)); err != nil {
 		return false, err
 	}
 
@@ -301,14 +363,16 @@ func PutDatapoint(sysap string, deviceId string, channelId string, datapointId s
 	return ok, nil
 }
 
-func PutVirtualDevice(sysap, serial string, message *VirtualDevice) (virtualSerial string, err error) {
-	httpUrl := fmt.Sprintf("http://%s%s%s/%s/%s", apiConfig.Host, ApiPathPrefix, "/api/rest/virtualdevice", sysap, serial)
+func (c *Client) PutVirtualDevice(ctx context.Context, sysap, serial string, message *VirtualDevice) (virtualSerial string, err error) {
+	httpUrl := fmt.Sprintf("%s://%s%s%s/%s/%s", c.scheme, c.host, ApiPathPrefix, "/api/rest/virtualdevice", sysap, serial)
 
 	var messageString []byte
-	messageString, err = json2.Marshal(message)
+	if messageString, err = json2.Marshal(message); err != nil {
+		return
+	}
 
 	var returnBody []byte
-	if returnBody, err = putRequest(httpUrl, bytes.NewBuffer(messageString)); err != nil {
+	if returnBody, err = c.putRequest(ctx, httpUrl, bytes.NewBuffer(messageString)); err != nil {
 		return
 	}
 
@@ -317,7 +381,7 @@ func PutVirtualDevice(sysap, serial string, message *VirtualDevice) (virtualSeri
 		return
 	}
 
-	for virtualSerial, devices := range result.ZeroSysAp.Devices {
+	for virtualSerial, devices := range result[sysap].Devices {
 		if devices.Serial == serial {
 			return virtualSerial, nil
 		}
@@ -325,51 +389,60 @@ func PutVirtualDevice(sysap, serial string, message *VirtualDevice) (virtualSeri
 	return "", fmt.Errorf("virtual Device PUT returned no device with serial %s (%s)", serial, returnBody)
 }
 
-func loadUrl(httpUrl string) ([]byte, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", httpUrl, nil)
+func (c *Client) loadUrl(ctx context.Context, httpUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpUrl, nil)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", apiConfig.Authentication)
+	req.Header.Set("Authorization", c.authentication)
 
-	if logLevel > 1 {
-		logger.Printf("getting %s ...\n", httpUrl)
+	if c.logLevel > 1 {
+		c.logger.Printf("getting %s ...\n", httpUrl)
 	}
 
 	var json []byte
 
-	response, err := client.Do(req)
+	var response *http.Response
+	response, err = c.httpClient.Do(req)
 	if err != nil {
-		logger.Printf("error getting %s: %s\n", httpUrl, err.Error())
+		c.logger.Printf("error getting %s: %s\n", httpUrl, err.Error())
 		return nil, err
 	}
-	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("GET url %s returned code %d (%s)", httpUrl, response.StatusCode, response.Status)
-	}
+	defer response.Body.Close()
 
 	json, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != 200 {
+		return nil, newAPIError(http.MethodGet, httpUrl, response, json)
+	}
 
-	return json, err
+	return json, nil
 }
 
-func putRequest(url string, data io.Reader) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPut, url, data)
+func (c *Client) putRequest(ctx context.Context, url string, data io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, data)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", apiConfig.Authentication)
+	req.Header.Set("Authorization", c.authentication)
 	var response *http.Response
-	response, err = client.Do(req)
+	response, err = c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-
-	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("PUT url %s returned code %d (%s)", url, response.StatusCode, response.Status)
-	}
+	defer response.Body.Close()
 
 	var body []byte
 	body, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != 200 {
+		return nil, newAPIError(http.MethodPut, url, response, body)
+	}
 
-	return body, err
+	return body, nil
 }
@@ -0,0 +1,90 @@
+package fahapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMultipleSysAPs(t *testing.T) {
+	const otherSysAP = "11111111-1111-1111-1111-111111111111"
+	f := newFakeSysAP(t, nil)
+	f.setSysAPs(map[string]map[string]*Device{
+		testSysAP:  {"ABB700000001": testSwitch("Kitchen", "0")},
+		otherSysAP: {"ABB700000001": testSwitch("Garage", "1")},
+	})
+	c := startClient(t, f)
+
+	if ids := c.SysAPIds(); len(ids) != 2 {
+		t.Fatalf("SysAPs %v, want both", ids)
+	}
+	kitchen := CastSAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000"))
+	garage := CastSAU(c.LookupChannelUnit(otherSysAP, "ABB700000001", "ch0000"))
+	if kitchen.SysApId != testSysAP || kitchen.On || garage.SysApId != otherSysAP || !garage.On {
+		t.Errorf("kitchen %s on %v, garage %s on %v", kitchen.SysApId, kitchen.On, garage.SysApId, garage.On)
+	}
+
+	// the same device id on another SysAP is another unit
+	conn := startLoop(t, f, c)
+	if err := conn.WriteJSON(WebsocketMessage{otherSysAP: {Datapoints: map[string]string{"ABB700000001/ch0000/odp0000": "0"}}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "garage off", func() bool { return !CastSAU(c.LookupChannelUnit(otherSysAP, "ABB700000001", "ch0000")).On })
+	if CastSAU(c.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000")).On {
+		t.Error("update of the other SysAP switched the kitchen")
+	}
+
+	if err := garage.SetOn(testContext(t), true); err != nil {
+		t.Fatal(err)
+	}
+	puts := f.recordedPuts()
+	if wantPath := "/api/rest/datapoint/" + otherSysAP + "/ABB700000001.ch0000.idp0000"; len(puts) != 1 || puts[0].Path != wantPath {
+		t.Errorf("PUTs %+v, want one to %s", puts, wantPath)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	f := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("Light", "0")})
+	c := startClient(t, f)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.ReadAndHydradteAllDevices(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadAndHydradteAllDevices with a cancelled context: %v", err)
+	}
+
+	f.setPutResult("OK", 300*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.PutDatapoint(ctx, testSysAP, "ABB700000001", "ch0000", "idp0000", "1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PutDatapoint with a deadline: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("PutDatapoint returned after %s, not at the deadline", elapsed)
+	}
+}
+
+func TestClientsAreIndependent(t *testing.T) {
+	first := newFakeSysAP(t, map[string]*Device{"ABB700000001": testSwitch("First", "1")})
+	second := newFakeSysAP(t, map[string]*Device{"ABB700000002": testSwitch("Second", "0")})
+	c1 := startClient(t, first)
+	c2 := startClient(t, second)
+
+	if len(c1.AllUnits()) != 1 || len(c2.AllUnits()) != 1 {
+		t.Fatalf("clients have %d and %d units, want one each", len(c1.AllUnits()), len(c2.AllUnits()))
+	}
+	if c1.LookupChannelUnit(testSysAP, "ABB700000002", "ch0000") != nil || c2.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000") != nil {
+		t.Error("a client sees the unit of the other one")
+	}
+
+	conn := startLoop(t, second, c2)
+	sendDatapoints(t, conn, map[string]string{"ABB700000002/ch0000/odp0000": "1"})
+	waitFor(t, "update of the second client", func() bool {
+		return CastSAU(c2.LookupChannelUnit(testSysAP, "ABB700000002", "ch0000")).On
+	})
+	if !CastSAU(c1.LookupChannelUnit(testSysAP, "ABB700000001", "ch0000")).On || len(c1.AllUnits()) != 1 {
+		t.Error("update of the second client changed the first one")
+	}
+}